@@ -17,44 +17,652 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type StorageType string
 
-const StorageTypeInMemory StorageType = "inmemory"
+const (
+	StorageTypeInMemory   StorageType = "inmemory"
+	StorageTypeFilesystem StorageType = "filesystem"
+	StorageTypeS3         StorageType = "s3"
+	StorageTypeGCS        StorageType = "gcs"
+	StorageTypeAzure      StorageType = "azure"
+)
+
+const (
+	// MirrorsAnnotation lists the comma separated upstream hosts a Registry mirrors, e.g. "docker.io".
+	// Images on those hosts are rewritten to the Registry in the namespaces labeled with ImageRewriteLabel.
+	// Only hosts matching the spec.proxy.remoteURL of the Registry are mirrored, and only for pods
+	// in the namespace of the Registry or in namespaces allowing it with MirrorRegistriesAnnotation.
+	MirrorsAnnotation = "registry-operator.dev/mirrors"
+	// MirrorRegistriesAnnotation lists the comma separated namespace/name of the Registries outside
+	// of a namespace its pod images may be rewritten to, e.g. "registry-system/docker-hub".
+	// It is set on the namespace by cluster admins, Registries in the namespace itself take precedence.
+	MirrorRegistriesAnnotation = "registry-operator.dev/mirror-registries"
+	// InjectPullSecretAnnotation adds the pull secret distributed for a mirroring Registry
+	// to the pods whose images are rewritten to it, when set to "true".
+	InjectPullSecretAnnotation = "registry-operator.dev/inject-pull-secret"
+	// ImageRewriteLabel opts a namespace into the rewriting of pod images to mirroring Registries
+	// when set to "enabled".
+	ImageRewriteLabel = "registry-operator.dev/image-rewrite"
+	// DistributionLabel opts a namespace into receiving the CA bundles and pull secrets of Registries
+	// when set to "enabled". It is set by cluster admins, the owners of a Registry cannot label namespaces.
+	DistributionLabel = "registry-operator.dev/distribution"
+	// RotateHTTPSecretAnnotation rotates the HTTP secret shared by the pods of a Registry whenever its value changes.
+	// Uploads in progress during the rotation have to be restarted.
+	RotateHTTPSecretAnnotation = "registry-operator.dev/rotate-http-secret"
+)
 
+// +kubebuilder:validation:XValidation:rule="self.type != 'filesystem' || has(self.filesystem)",message="filesystem must be set when storage type is filesystem"
+// +kubebuilder:validation:XValidation:rule="self.type != 's3' || has(self.s3)",message="s3 must be set when storage type is s3"
+// +kubebuilder:validation:XValidation:rule="self.type != 'gcs' || has(self.gcs)",message="gcs must be set when storage type is gcs"
+// +kubebuilder:validation:XValidation:rule="self.type != 'azure' || has(self.azure)",message="azure must be set when storage type is azure"
 type Storage struct {
 	// +kubebuilder:default="inmemory"
-	// +kubebuilder:validation:Enum=inmemory
+	// +kubebuilder:validation:Enum=inmemory;filesystem;s3;gcs;azure
 	Type StorageType `json:"type"`
+
+	// Filesystem configures the filesystem storage driver.
+	// +optional
+	Filesystem *FilesystemStorage `json:"filesystem,omitempty"`
+
+	// S3 configures the S3 storage driver.
+	// +optional
+	S3 *S3Storage `json:"s3,omitempty"`
+
+	// GCS configures the Google Cloud Storage driver.
+	// +optional
+	GCS *GCSStorage `json:"gcs,omitempty"`
+
+	// Azure configures the Azure Blob Storage driver.
+	// +optional
+	Azure *AzureStorage `json:"azure,omitempty"`
+
+	// Delete configures the deletion of blobs and manifests through the registry API.
+	// +optional
+	Delete *StorageDelete `json:"delete,omitempty"`
+
+	// Maintenance configures the background maintenance of the storage.
+	// +optional
+	Maintenance *StorageMaintenance `json:"maintenance,omitempty"`
+}
+
+// StorageDelete configures the deletion of blobs and manifests.
+type StorageDelete struct {
+	// Enabled allows clients to delete blobs and manifests. Deleted blobs are only reclaimed by garbage collection.
+	Enabled bool `json:"enabled"`
+}
+
+// StorageMaintenance configures the background maintenance of the storage.
+type StorageMaintenance struct {
+	// UploadPurging configures the removal of abandoned uploads.
+	// +optional
+	UploadPurging *UploadPurging `json:"uploadPurging,omitempty"`
+}
+
+// UploadPurging configures the periodic removal of uploads that were never completed.
+type UploadPurging struct {
+	// Enabled turns upload purging on or off.
+	Enabled bool `json:"enabled"`
+
+	// Age is how old an upload has to be before it is removed.
+	// When omitted, the registry default of 168h applies.
+	// +optional
+	Age *metav1.Duration `json:"age,omitempty"`
+
+	// Interval is how often abandoned uploads are looked for.
+	// When omitted, the registry default of 24h applies.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// DryRun only logs the uploads that would be removed.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// FilesystemStorage stores registry data on a PersistentVolumeClaim managed by the operator.
+type FilesystemStorage struct {
+	// Size is the requested capacity of the PersistentVolumeClaim.
+	// +kubebuilder:default="10Gi"
+	Size resource.Quantity `json:"size,omitempty"`
+
+	// StorageClassName is the name of the StorageClass used by the PersistentVolumeClaim.
+	// When omitted, the cluster default StorageClass is used.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// AccessModes are the access modes requested for the PersistentVolumeClaim.
+	// +kubebuilder:default={"ReadWriteOnce"}
+	// +kubebuilder:validation:MaxItems=4
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+}
+
+// S3Storage stores registry data in an Amazon S3 or S3-compatible (e.g. MinIO) bucket.
+type S3Storage struct {
+	// Bucket is the name of the bucket in which registry data is stored.
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+
+	// Region is the region in which the bucket exists.
+	// +kubebuilder:validation:MinLength=1
+	Region string `json:"region"`
+
+	// RegionEndpoint is the endpoint of an S3-compatible storage service, e.g. http://minio.minio.svc:9000.
+	// +optional
+	RegionEndpoint string `json:"regionEndpoint,omitempty"`
+
+	// RootDirectory is a prefix applied to all keys stored in the bucket.
+	// +optional
+	RootDirectory string `json:"rootDirectory,omitempty"`
+
+	// Encrypt enables server-side encryption of stored objects.
+	// +optional
+	Encrypt bool `json:"encrypt,omitempty"`
+
+	// KeyID is the KMS key ID used for server-side encryption.
+	// +optional
+	KeyID string `json:"keyID,omitempty"`
+
+	// CredentialsSecretRef references a Secret with the accessKey and secretKey keys.
+	// The keys are injected into the registry as environment variables.
+	// When omitted, the registry uses the default AWS credentials chain.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// GCSStorage stores registry data in a Google Cloud Storage bucket.
+type GCSStorage struct {
+	// Bucket is the name of the bucket in which registry data is stored.
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+
+	// RootDirectory is a prefix applied to all keys stored in the bucket.
+	// +optional
+	RootDirectory string `json:"rootDirectory,omitempty"`
+
+	// CredentialsSecretRef references a Secret with the service account JSON key under the key.json key.
+	// The key is mounted into the registry as a file.
+	// When omitted, the registry uses the default Google application credentials.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// AzureStorage stores registry data in an Azure Blob Storage container.
+type AzureStorage struct {
+	// AccountName is the name of the Azure storage account.
+	// +kubebuilder:validation:MinLength=1
+	AccountName string `json:"accountName"`
+
+	// Container is the name of the container in which registry data is stored.
+	// +kubebuilder:validation:MinLength=1
+	Container string `json:"container"`
+
+	// Realm is the domain name suffix of the Azure Storage service endpoint.
+	// +kubebuilder:default="core.windows.net"
+	// +optional
+	Realm string `json:"realm,omitempty"`
+
+	// CredentialsSecretRef references a Secret with the storage account key under the accountKey key.
+	// The key is injected into the registry as an environment variable.
+	// +kubebuilder:validation:Required
+	CredentialsSecretRef corev1.LocalObjectReference `json:"credentialsSecretRef"`
 }
 
 // RegistrySpec defines the desired state of Registry.
+// +kubebuilder:validation:XValidation:rule="!has(self.garbageCollection) || self.storage.type != 'inmemory'",message="garbageCollection cannot be set with inmemory storage"
+// +kubebuilder:validation:XValidation:rule="!(has(self.proxy) && has(self.garbageCollection))",message="garbageCollection cannot be set on a proxy"
+// +kubebuilder:validation:XValidation:rule="!has(self.proxy) || !has(self.storage.delete) || !self.storage.delete.enabled",message="storage.delete cannot be enabled on a proxy"
+// +kubebuilder:validation:XValidation:rule="self.storage.type != 'filesystem' || !has(self.replicas) || self.replicas <= 1 || (has(self.storage.filesystem) && has(self.storage.filesystem.accessModes) && 'ReadWriteMany' in self.storage.filesystem.accessModes)",message="replicas cannot be greater than 1 unless the filesystem storage is ReadWriteMany"
 type RegistrySpec struct {
 	// +kubebuilder:default={"type": "inmemory"}
 	// +kubebuilder:validation:Required
 	Storage Storage `json:"storage"`
+
+	// Replicas is the number of desired registry pods.
+	// With filesystem storage, more than one replica requires the ReadWriteMany access mode,
+	// as the replicas share the claim and may be scheduled on different nodes.
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Service configures the Service exposing the registry.
+	// +kubebuilder:default={}
+	// +optional
+	Service RegistryService `json:"service,omitempty"`
+
+	// Exposure configures how the registry is exposed outside of the cluster.
+	// +optional
+	Exposure *Exposure `json:"exposure,omitempty"`
+
+	// Auth configures how clients authenticate to the registry.
+	// When omitted, the registry accepts anonymous pulls and pushes.
+	// +optional
+	Auth *RegistryAuth `json:"auth,omitempty"`
+
+	// TLS configures the certificate the registry serves HTTPS with.
+	// When omitted, the registry serves plain HTTP.
+	// It cannot be set when the registry is exposed through an HTTPRoute, which routes plain HTTP to the Service.
+	// +optional
+	TLS *RegistryTLS `json:"tls,omitempty"`
+
+	// Distribution copies the CA bundle and pull credentials of the registry to other namespaces.
+	// +optional
+	Distribution *RegistryDistribution `json:"distribution,omitempty"`
+
+	// Proxy turns the registry into a pull-through cache of a remote registry.
+	// A proxy is read-only: pushes and deletes are rejected, and RegistryAccessPolicies only grant pulls on it.
+	// +optional
+	Proxy *RegistryProxy `json:"proxy,omitempty"`
+
+	// GarbageCollection periodically removes the blobs no longer referenced by any manifest.
+	// The registry is held read-only while garbage collection runs.
+	// +optional
+	GarbageCollection *GarbageCollection `json:"garbageCollection,omitempty"`
+
+	// Maintenance puts a live registry into maintenance mode, e.g. for storage migrations and backups.
+	// +optional
+	Maintenance *RegistryMaintenance `json:"maintenance,omitempty"`
+
+	// Cache configures the cache of blob descriptors.
+	// Registries with several replicas need a shared redis cache to answer HEAD requests consistently.
+	// +optional
+	Cache *RegistryCache `json:"cache,omitempty"`
+
+	// Notifications configures the webhooks the registry notifies of pushes, pulls and deletes.
+	// +optional
+	Notifications *RegistryNotifications `json:"notifications,omitempty"`
+}
+
+// RegistryNotifications configures the notification endpoints of the registry.
+type RegistryNotifications struct {
+	// Endpoints are the webhooks every event is sent to.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MinItems=1
+	Endpoints []NotificationEndpoint `json:"endpoints"`
+}
+
+// NotificationEndpoint configures a webhook the registry sends its events to.
+type NotificationEndpoint struct {
+	// Name identifies the endpoint in the registry logs and metrics.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// URL is the URL the events are posted to.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// Headers are added to every request sent to the endpoint.
+	// +optional
+	Headers []NotificationHeader `json:"headers,omitempty"`
+
+	// Timeout is how long the registry waits for the endpoint to respond.
+	// When omitted, the registry default of 1s applies.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Threshold is the number of failed requests after which the registry backs off.
+	// When omitted, the registry default of 10 applies.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Threshold int32 `json:"threshold,omitempty"`
+
+	// Backoff is how long the registry waits before retrying once the threshold is reached.
+	// When omitted, the registry default of 1s applies.
+	// +optional
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+
+	// Ignore filters the events that are not sent to the endpoint.
+	// +optional
+	Ignore *NotificationIgnore `json:"ignore,omitempty"`
+}
+
+// NotificationHeader is an HTTP header sent to a notification endpoint.
+// +kubebuilder:validation:XValidation:rule="has(self.value) != has(self.secretKeyRef)",message="exactly one of value or secretKeyRef must be set"
+type NotificationHeader struct {
+	// Name is the name of the header.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Value is the value of the header, written in plain text into the registry configuration.
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// SecretKeyRef selects a key of a Secret the value of the header is taken from.
+	// The value is passed to the registry through a Secret generated by the operator,
+	// it never reaches the ConfigMap.
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// NotificationIgnore filters the events sent to a notification endpoint.
+type NotificationIgnore struct {
+	// MediaTypes are the media types of the manifests whose events are not sent.
+	// +optional
+	MediaTypes []string `json:"mediaTypes,omitempty"`
+
+	// Actions are the actions whose events are not sent.
+	// +optional
+	Actions []NotificationAction `json:"actions,omitempty"`
+}
+
+// NotificationAction is an action of the registry events are emitted for.
+// +kubebuilder:validation:Enum=pull;push;mount;delete
+type NotificationAction string
+
+// CacheType is the backend of the blob descriptor cache.
+type CacheType string
+
+const (
+	CacheTypeInMemory CacheType = "inmemory"
+	CacheTypeRedis    CacheType = "redis"
+)
+
+// RegistryCache configures the blob descriptor cache of the registry.
+// +kubebuilder:validation:XValidation:rule="self.type == 'redis' || !has(self.redis)",message="redis can only be set when type is redis"
+type RegistryCache struct {
+	// Type is the backend of the cache.
+	// +kubebuilder:validation:Enum=inmemory;redis
+	Type CacheType `json:"type"`
+
+	// Redis references an external redis instance.
+	// When omitted with the redis type, the operator deploys a redis alongside the registry.
+	// +optional
+	Redis *RedisCache `json:"redis,omitempty"`
 }
 
-// +kubebuilder:validation:Enum=Pending;Running;Deleting
+// RedisCache references an external redis instance.
+type RedisCache struct {
+	// Addr is the host and port of the redis instance.
+	// +kubebuilder:validation:MinLength=1
+	Addr string `json:"addr"`
+
+	// PasswordSecretRef references a Secret with the password key the registry authenticates to redis with.
+	// The key is injected into the registry as an environment variable.
+	// +optional
+	PasswordSecretRef *corev1.LocalObjectReference `json:"passwordSecretRef,omitempty"`
+
+	// DB is the number of the redis database.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DB int32 `json:"db,omitempty"`
+}
+
+// RegistryMaintenance configures the maintenance mode of the registry.
+type RegistryMaintenance struct {
+	// ReadOnly rejects pushes and deletes while pulls keep being served.
+	// Toggling it rolls the registry pods, the stored data is kept.
+	// +optional
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// GarbageCollection configures the CronJob running garbage collection against the registry storage.
+type GarbageCollection struct {
+	// Schedule is the cron schedule of the garbage collection, e.g. "0 3 * * 0".
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// DeleteUntagged also removes the manifests that are not referenced by any tag.
+	// +optional
+	DeleteUntagged bool `json:"deleteUntagged,omitempty"`
+
+	// DryRun only reports what would be removed.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// RegistryProxy configures the remote registry a pull-through cache mirrors.
+type RegistryProxy struct {
+	// RemoteURL is the URL of the remote registry, e.g. https://registry-1.docker.io.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	RemoteURL string `json:"remoteURL"`
+
+	// CredentialsSecretRef references a Secret with the username and password keys
+	// the registry authenticates to the remote registry with.
+	// The keys are injected into the registry as environment variables.
+	// When omitted, the remote registry is accessed anonymously.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+
+	// TTL is how long cached content is kept before it is fetched again from the remote registry.
+	// It is only honoured by distribution v3, the registry:2 image the operator runs keeps
+	// cached content for its default of 7 days.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+// RegistryDistribution configures which namespaces receive the CA bundle and pull credentials of the registry.
+// The copies are named <registry>-ca-bundle and <registry>-pull-secret and kept in sync by the operator.
+type RegistryDistribution struct {
+	// NamespaceSelector selects the namespaces the copies are kept in. It cannot be empty.
+	// Only namespaces opted in with the registry-operator.dev/distribution=enabled label are selected,
+	// and kube-* system namespaces never are.
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+
+	// CAConfigMapRef references a ConfigMap in the registry namespace holding the CA bundle under the ca.crt key.
+	// Defaults to the <registry>-ca ConfigMap when the registry serves a self-signed certificate.
+	// +optional
+	CAConfigMapRef *corev1.LocalObjectReference `json:"caConfigMapRef,omitempty"`
+
+	// CredentialsSecretRef references a Secret in the registry namespace holding the username and password keys
+	// the kubernetes.io/dockerconfigjson copies are rendered from, such as the Secret of a RegistryUser.
+	// Defaults to the <registry>-admin-credentials Secret when the operator generates the htpasswd credentials.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// RegistryTLS configures the certificate the registry serves.
+// +kubebuilder:validation:XValidation:rule="has(self.secretName) != (has(self.selfSigned) && self.selfSigned)",message="exactly one of secretName and selfSigned must be set"
+type RegistryTLS struct {
+	// SecretName references a kubernetes.io/tls Secret in the registry namespace holding the serving certificate.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// SelfSigned makes the operator generate a CA and a serving certificate for the Service DNS names
+	// and the exposure host, and renew them before they expire. The CA certificate is published
+	// under the ca.crt key of the <registry>-ca ConfigMap.
+	// +optional
+	SelfSigned bool `json:"selfSigned,omitempty"`
+}
+
+// RegistryAuth configures how clients authenticate to the registry.
+// +kubebuilder:validation:XValidation:rule="!(has(self.htpasswd) && has(self.token))",message="only one of htpasswd and token can be set"
+type RegistryAuth struct {
+	// Htpasswd enables basic authentication against an htpasswd file generated by the operator.
+	// +optional
+	Htpasswd *HtpasswdAuth `json:"htpasswd,omitempty"`
+
+	// Token enables token authentication against the token issuer built into the operator.
+	// Clients log in with Kubernetes ServiceAccount tokens, the registry is identified as <namespace>/<name>.
+	// Access is granted by the RegistryAccessPolicies referencing the registry.
+	// +optional
+	Token *TokenAuth `json:"token,omitempty"`
+}
+
+// TokenAuth configures token authentication of the registry.
+type TokenAuth struct{}
+
+// HtpasswdAuth configures basic authentication of the registry.
+type HtpasswdAuth struct {
+	// Realm is the realm presented to clients in authentication challenges.
+	// +kubebuilder:default="Registry Realm"
+	// +optional
+	Realm string `json:"realm,omitempty"`
+
+	// CredentialsSecretRef references a Secret in the registry namespace
+	// whose keys are the usernames and values are the passwords of the registry users.
+	// When omitted, the operator generates a kubernetes.io/basic-auth Secret named
+	// <registry>-admin-credentials holding random credentials for the admin user.
+	// The RegistryUsers referencing the registry are added to these credentials.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// RegistryService configures the Service exposing the registry.
+type RegistryService struct {
+	// Type is the type of the Service.
+	// +kubebuilder:default="ClusterIP"
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +optional
+	Type corev1.ServiceType `json:"type,omitempty"`
+
+	// Port is the port on which the Service exposes the registry.
+	// +kubebuilder:default=5000
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Annotations are added to the Service, e.g. to configure cloud load balancers.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Pending;Running;Updating;Deleting
 type RegistryPhase string
 
 const (
 	RegistryPhasePending  RegistryPhase = "Pending"
 	RegistryPhaseRunning  RegistryPhase = "Running"
+	RegistryPhaseUpdating RegistryPhase = "Updating"
 	RegistryPhaseDeleting RegistryPhase = "Deleting"
 )
 
+// Condition types reported in RegistryStatus.
+const (
+	// ConditionTypeReady indicates that the registry is serving requests.
+	ConditionTypeReady = "Ready"
+	// ConditionTypeConfigValid indicates that the registry configuration could be rendered from the spec.
+	ConditionTypeConfigValid = "ConfigValid"
+	// ConditionTypeStorageReady indicates that the storage backing the registry is available.
+	ConditionTypeStorageReady = "StorageReady"
+	// ConditionTypeProgressing indicates that the registry workload is being rolled out.
+	ConditionTypeProgressing = "Progressing"
+	// ConditionTypeDegraded indicates that the registry failed to reach the desired state.
+	ConditionTypeDegraded = "Degraded"
+	// ConditionTypeReadOnly indicates that the registry rejects pushes and deletes.
+	ConditionTypeReadOnly = "ReadOnly"
+)
+
+// Exposure configures an Ingress or a Gateway API HTTPRoute in front of the registry Service.
+// +kubebuilder:validation:XValidation:rule="has(self.ingress) != has(self.httpRoute)",message="exactly one of ingress or httpRoute must be set"
+type Exposure struct {
+	// Host is the external hostname of the registry.
+	// +kubebuilder:validation:MinLength=1
+	Host string `json:"host"`
+
+	// TLS enables HTTPS on the external endpoint.
+	// +optional
+	TLS *ExposureTLS `json:"tls,omitempty"`
+
+	// Annotations are added to the created resource. They are merged over the
+	// annotations the operator sets to allow large image layer uploads.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Ingress exposes the registry through a networking.k8s.io Ingress.
+	// +optional
+	Ingress *IngressExposure `json:"ingress,omitempty"`
+
+	// HTTPRoute exposes the registry through a gateway.networking.k8s.io HTTPRoute.
+	// The Gateway terminates TLS and sends plain HTTP to the registry, so spec.tls cannot be set with it.
+	// +optional
+	HTTPRoute *HTTPRouteExposure `json:"httpRoute,omitempty"`
+}
+
+// ExposureTLS configures HTTPS on the external endpoint.
+type ExposureTLS struct {
+	// SecretName is the name of the Secret holding the certificate for the host.
+	// It is used by the Ingress, for an HTTPRoute TLS is terminated by the parent Gateway.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// IngressExposure configures the Ingress exposing the registry.
+type IngressExposure struct {
+	// ClassName is the name of the IngressClass handling the Ingress.
+	// +optional
+	ClassName *string `json:"className,omitempty"`
+}
+
+// HTTPRouteExposure configures the HTTPRoute exposing the registry.
+type HTTPRouteExposure struct {
+	// ParentRefs are the Gateways the HTTPRoute attaches to.
+	// +kubebuilder:validation:MinItems=1
+	ParentRefs []ParentReference `json:"parentRefs"`
+}
+
+// ParentReference references a Gateway the HTTPRoute attaches to.
+type ParentReference struct {
+	// Name is the name of the Gateway.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the Gateway. Defaults to the namespace of the Registry.
+	// +optional
+	Namespace *string `json:"namespace,omitempty"`
+
+	// SectionName is the name of the Gateway listener to attach to.
+	// +optional
+	SectionName *string `json:"sectionName,omitempty"`
+}
+
 // RegistryStatus defines the observed state of Registry.
 type RegistryStatus struct {
 	// +kubebuilder:default="Pending"
 	Phase RegistryPhase `json:"phase"`
+
+	// Conditions represent the latest available observations of the registry state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed by the operator.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Image is the registry image run by the workload.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ConfigHash is the hash of the rendered registry configuration.
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+
+	// Replicas is the number of registry pods observed on the Deployment.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Selector is the label selector of the registry pods, used by the scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// URL is the in-cluster URL under which the registry is reachable.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// ExternalURL is the URL under which the registry is reachable from outside of the cluster.
+	// +optional
+	ExternalURL string `json:"externalURL,omitempty"`
+
+	// GarbageCollectionJob is the name of the garbage collection Job the registry is held read-only for.
+	// +optional
+	GarbageCollectionJob string `json:"garbageCollectionJob,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
-// +kubebuildre:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="The current phase of the registry"
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="The current phase of the registry"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether the registry is ready"
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".status.url",description="The in-cluster URL of the registry"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // Registry is the Schema for the registries API.
 type Registry struct {
 	metav1.TypeMeta   `json:",inline"`
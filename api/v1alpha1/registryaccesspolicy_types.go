@@ -0,0 +1,100 @@
+/*
+Copyright 2024 registry-operator authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=pull;push;delete
+type RegistryAction string
+
+const (
+	RegistryActionPull   RegistryAction = "pull"
+	RegistryActionPush   RegistryAction = "push"
+	RegistryActionDelete RegistryAction = "delete"
+)
+
+// +kubebuilder:validation:Enum=User;Group;ServiceAccount
+type SubjectKind string
+
+const (
+	SubjectKindUser           SubjectKind = "User"
+	SubjectKindGroup          SubjectKind = "Group"
+	SubjectKindServiceAccount SubjectKind = "ServiceAccount"
+)
+
+// Subject identifies a Kubernetes user, group or ServiceAccount.
+type Subject struct {
+	Kind SubjectKind `json:"kind"`
+
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the ServiceAccount. Defaults to the namespace of the policy.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// AccessRule grants subjects actions on the repositories matching any of the patterns.
+type AccessRule struct {
+	// +kubebuilder:validation:MinItems=1
+	Subjects []Subject `json:"subjects"`
+
+	// Repositories are glob patterns of repository names. "*" matches any characters but "/",
+	// "**" matches any characters including "/", e.g. "team-a/**" matches all repositories of team-a.
+	// +kubebuilder:validation:MinItems=1
+	Repositories []string `json:"repositories"`
+
+	// +kubebuilder:validation:MinItems=1
+	Actions []RegistryAction `json:"actions"`
+}
+
+// RegistryAccessPolicySpec defines the access granted by the RegistryAccessPolicy.
+type RegistryAccessPolicySpec struct {
+	// RegistryRef references the Registry in the namespace of the policy the access is granted to.
+	// The registry must use token authentication.
+	RegistryRef corev1.LocalObjectReference `json:"registryRef"`
+
+	// +kubebuilder:validation:MinItems=1
+	Rules []AccessRule `json:"rules"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Registry",type="string",JSONPath=".spec.registryRef.name",description="The registry the access is granted to"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// RegistryAccessPolicy is the Schema for the registryaccesspolicies API.
+// It grants Kubernetes subjects access to repositories of a Registry with token authentication.
+type RegistryAccessPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RegistryAccessPolicySpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+// RegistryAccessPolicyList contains a list of RegistryAccessPolicy.
+type RegistryAccessPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RegistryAccessPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RegistryAccessPolicy{}, &RegistryAccessPolicyList{})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 registry-operator authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RotateAnnotation rotates the password of a RegistryUser whenever its value changes.
+const RotateAnnotation = "registry-operator.dev/rotate"
+
+// RegistryUserSpec defines the desired state of RegistryUser.
+type RegistryUserSpec struct {
+	// RegistryRef references the Registry in the namespace of the user the account is created in.
+	// The registry must use htpasswd authentication.
+	RegistryRef corev1.LocalObjectReference `json:"registryRef"`
+
+	// Username is the name the user logs in with. Defaults to the name of the RegistryUser.
+	// +kubebuilder:validation:Pattern=`^[^:\s]+$`
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// ExpiresAt is the time after which the user can no longer log in.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// RotationInterval is how often the password of the user is regenerated, e.g. "720h".
+	// When omitted, the password is rotated only on request through the registry-operator.dev/rotate annotation.
+	// +optional
+	RotationInterval *metav1.Duration `json:"rotationInterval,omitempty"`
+}
+
+// RegistryUserStatus defines the observed state of RegistryUser.
+type RegistryUserStatus struct {
+	// Conditions represent the latest available observations of the user state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// SecretName is the name of the kubernetes.io/dockerconfigjson Secret holding the credentials of the user.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// LastRotationTime is when the password of the user was last generated.
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+
+	// ObservedRotation is the value of the rotate annotation the password was last generated for.
+	// +optional
+	ObservedRotation string `json:"observedRotation,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Registry",type="string",JSONPath=".spec.registryRef.name",description="The registry the user is created in"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether the user can log in"
+// +kubebuilder:printcolumn:name="Secret",type="string",JSONPath=".status.secretName",description="The Secret holding the credentials"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// RegistryUser is the Schema for the registryusers API.
+// It is a robot account of a Registry with htpasswd authentication.
+type RegistryUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RegistryUserSpec   `json:"spec"`
+	Status RegistryUserStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// RegistryUserList contains a list of RegistryUser.
+type RegistryUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RegistryUser `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RegistryUser{}, &RegistryUserList{})
+}
+
+// EffectiveUsername returns the name the user logs in with.
+func (u *RegistryUser) EffectiveUsername() string {
+	if u.Spec.Username != "" {
+		return u.Spec.Username
+	}
+	return u.Name
+}
@@ -17,25 +17,35 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
 	"github.com/registry-operator/registry-operator/internal/controller"
+	"github.com/registry-operator/registry-operator/internal/tokenauth"
+	webhookcorev1 "github.com/registry-operator/registry-operator/internal/webhook/v1"
+	webhookregistryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/internal/webhook/v1alpha1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -46,6 +56,9 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	// Gateway API types are registered even when its CRDs are not installed,
+	// HTTPRoutes are only created for registries that request them.
+	utilruntime.Must(gatewayv1.AddToScheme(scheme))
 
 	utilruntime.Must(registryoperatordevv1alpha1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
@@ -57,6 +70,11 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var tokenAuthAddr string
+	var tokenAuthRealm string
+	var tokenAuthSigningKeySecret string
+	var tokenAuthCertDir string
+	var tokenAuthAudience string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -66,6 +84,16 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&tokenAuthAddr, "token-auth-bind-address", ":5001", "The address the token endpoint binds to.")
+	flag.StringVar(&tokenAuthRealm, "token-auth-realm", "",
+		"The URL under which registry clients reach the token endpoint. "+
+			"Token authentication of registries is enabled only when it is set.")
+	flag.StringVar(&tokenAuthSigningKeySecret, "token-auth-signing-key-secret", "registry-operator-token-signing-key",
+		"The name of the Secret in the operator namespace holding the key tokens are signed with.")
+	flag.StringVar(&tokenAuthCertDir, "token-auth-cert-dir", "/tmp/k8s-token-auth-server/serving-certs",
+		"The directory holding the tls.crt and tls.key files the token endpoint is served with.")
+	flag.StringVar(&tokenAuthAudience, "token-auth-audience", "registry-operator",
+		"The audience the Kubernetes tokens of registry clients must be issued for.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -96,6 +124,13 @@ func main() {
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
+		// Secrets and ConfigMaps are read from the API server instead of being cached cluster-wide,
+		// the controllers only watch their metadata.
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				DisableFor: []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}},
+			},
+		},
 		Metrics: metricsserver.Options{
 			BindAddress:   metricsAddr,
 			SecureServing: secureMetrics,
@@ -122,11 +157,62 @@ func main() {
 		os.Exit(1)
 	}
 
-	registryReconciler := controller.NewReconciler(mgr.GetClient(), mgr.GetScheme())
+	var tokenAuth *factories.TokenAuthOptions
+	if tokenAuthRealm != "" {
+		namespace := os.Getenv("POD_NAMESPACE")
+		if namespace == "" {
+			setupLog.Error(nil, "POD_NAMESPACE must be set to enable token authentication")
+			os.Exit(1)
+		}
+		key, err := tokenauth.LoadOrCreateSigningKey(context.Background(), mgr.GetAPIReader(), mgr.GetClient(),
+			types.NamespacedName{Namespace: namespace, Name: tokenAuthSigningKeySecret})
+		if err != nil {
+			setupLog.Error(err, "unable to load the token signing key")
+			os.Exit(1)
+		}
+		tokenServer := &tokenauth.Server{
+			Addr:      tokenAuthAddr,
+			CertDir:   tokenAuthCertDir,
+			TLSOpts:   tlsOpts,
+			Audiences: []string{tokenAuthAudience},
+			Client:    mgr.GetClient(),
+			Issuer: &tokenauth.TokenIssuer{
+				Key:        key,
+				Expiration: 5 * time.Minute,
+			},
+			Authorizer: &tokenauth.PolicyAuthorizer{Client: mgr.GetClient()},
+		}
+		if err = mgr.Add(tokenServer); err != nil {
+			setupLog.Error(err, "unable to set up the token server")
+			os.Exit(1)
+		}
+		tokenAuth = &factories.TokenAuthOptions{
+			Realm:          tokenAuthRealm,
+			Issuer:         tokenauth.Issuer,
+			RootCertBundle: key.Certificate,
+		}
+	}
+
+	registryReconciler := controller.NewReconciler(mgr.GetClient(), mgr.GetScheme(), tokenAuth)
 	if err = registryReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Registry")
 		os.Exit(1)
 	}
+	if err = controller.NewRegistryUserReconciler(mgr.GetClient()).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RegistryUser")
+		os.Exit(1)
+	}
+	// Webhooks are disabled when running the operator locally, without the serving certificate.
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookcorev1.SetupPodWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
+			os.Exit(1)
+		}
+		if err = webhookregistryoperatordevv1alpha1.SetupRegistryWebhookWithManager(mgr, tokenAuth); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Registry")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -1,3 +1,14 @@
 package internal
 
 const RegistryFinalizer = "registry-operator.dev/finalizer"
+
+// ConfigHashAnnotation holds the hash of the desired state a child resource was rendered from.
+// The operator compares it with a freshly rendered hash to detect spec changes.
+const ConfigHashAnnotation = "registry-operator.dev/config-hash"
+
+// SecretsHashAnnotation holds the hash of the Secrets consumed by the registry pods.
+const SecretsHashAnnotation = "registry-operator.dev/secrets-hash"
+
+// RegistryNamespaceLabel holds the namespace of the registry on the resources the operator
+// copies to other namespaces, which cannot carry owner references to the registry.
+const RegistryNamespaceLabel = "registry-operator.dev/registry-namespace"
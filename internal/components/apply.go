@@ -0,0 +1,407 @@
+package components
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// desiredChildren renders the child resources the registry spec currently asks for.
+// The Deployment goes last, so that everything it mounts exists before its pods are rolled.
+func (ro *RegistryOperations) desiredChildren(
+	ctx context.Context,
+	registry *registryoperatordevv1alpha1.Registry,
+) ([]client.Object, error) {
+	configMap, err := ro.ConfigMapFactory.NewConfigMap(registry)
+	if err != nil {
+		return nil, err
+	}
+	service, err := ro.ServiceFactory.NewService(registry)
+	if err != nil {
+		return nil, err
+	}
+	children := []client.Object{configMap, service}
+
+	if exposure := registry.Spec.Exposure; exposure != nil {
+		if exposure.Ingress != nil {
+			ingress, err := ro.IngressFactory.NewIngress(registry)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, ingress)
+		}
+		if exposure.HTTPRoute != nil {
+			route, err := ro.HTTPRouteFactory.NewHTTPRoute(registry)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, route)
+		}
+	}
+
+	// Redis is rolled out before the registry pods connecting to it.
+	if factories.ManagedRedis(registry) {
+		redisService, err := ro.RedisFactory.NewRedisService(registry)
+		if err != nil {
+			return nil, err
+		}
+		redisDeployment, err := ro.RedisFactory.NewRedisDeployment(registry)
+		if err != nil {
+			return nil, err
+		}
+		err = ro.setSecretsHash(ctx, registry.Namespace, &redisDeployment.Spec.Template)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, redisService, redisDeployment)
+	}
+
+	if registry.Spec.GarbageCollection != nil {
+		cronJob, err := ro.CronJobFactory.NewGarbageCollectionCronJob(registry)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, cronJob)
+	}
+
+	deployment, err := ro.DeploymentFactory.NewDeployment(registry)
+	if err != nil {
+		return nil, err
+	}
+	err = ro.setSecretsHash(ctx, registry.Namespace, &deployment.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	return append(children, deployment), nil
+}
+
+// staleChildren returns the resources the registry spec no longer asks for.
+// They are only removed when controlled by the registry, so that Secrets of users sharing a name are kept.
+func (ro *RegistryOperations) staleChildren(registry *registryoperatordevv1alpha1.Registry) []client.Object {
+	objectMeta := metav1.ObjectMeta{
+		Name:      registry.Name,
+		Namespace: registry.Namespace,
+	}
+	exposure := registry.Spec.Exposure
+	var stale []client.Object
+	if exposure == nil || exposure.Ingress == nil {
+		stale = append(stale, &networkingv1.Ingress{ObjectMeta: objectMeta})
+	}
+	if exposure == nil || exposure.HTTPRoute == nil {
+		stale = append(stale, &gatewayv1.HTTPRoute{ObjectMeta: objectMeta})
+	}
+	if !htpasswdEnabled(registry) {
+		stale = append(stale, &apiv1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      factories.HtpasswdSecretName(registry),
+			Namespace: registry.Namespace,
+		}})
+	}
+	if registry.Spec.GarbageCollection == nil {
+		stale = append(stale, &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{
+			Name:      factories.GarbageCollectionCronJobName(registry),
+			Namespace: registry.Namespace,
+		}})
+	}
+	if !factories.ManagedRedis(registry) {
+		redisMeta := metav1.ObjectMeta{
+			Name:      factories.RedisName(registry),
+			Namespace: registry.Namespace,
+		}
+		stale = append(stale,
+			&appsv1.Deployment{ObjectMeta: redisMeta},
+			&apiv1.Service{ObjectMeta: redisMeta},
+			&apiv1.Secret{ObjectMeta: redisMeta},
+		)
+	}
+	if !factories.HasSecretHeaders(registry) {
+		stale = append(stale, &apiv1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      factories.NotificationsSecretName(registry),
+			Namespace: registry.Namespace,
+		}})
+	}
+	if !selfSignedTLS(registry) {
+		stale = append(stale,
+			&apiv1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name:      registry.Name + "-tls",
+				Namespace: registry.Namespace,
+			}},
+			&apiv1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name:      factories.CASecretName(registry),
+				Namespace: registry.Namespace,
+			}},
+			&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name:      factories.CAConfigMapName(registry),
+				Namespace: registry.Namespace,
+			}},
+		)
+	}
+	return stale
+}
+
+// getChild fetches the live counterpart of a rendered child resource.
+// Kinds that are not installed in the cluster are reported as missing.
+func (ro *RegistryOperations) getChild(ctx context.Context, desired client.Object) (client.Object, bool, error) {
+	existing := desired.DeepCopyObject().(client.Object)
+	err := ro.Client.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	if err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return existing, true, nil
+}
+
+// kind returns the kind of the object for logging purposes.
+func (ro *RegistryOperations) kind(obj client.Object) string {
+	gvk, err := apiutil.GVKForObject(obj, ro.Client.Scheme())
+	if err != nil {
+		return fmt.Sprintf("%T", obj)
+	}
+	return gvk.Kind
+}
+
+// upToDate reports whether the live child resource matches the rendered one.
+// The hash annotation catches spec changes, while the derivative comparison catches manual edits
+// of the fields the operator sets, ignoring the ones defaulted by the API server.
+func upToDate(existing, desired client.Object) bool {
+	if existing.GetAnnotations()[internal.ConfigHashAnnotation] != desired.GetAnnotations()[internal.ConfigHashAnnotation] {
+		return false
+	}
+	return equality.Semantic.DeepDerivative(desired, existing)
+}
+
+// CheckRegistryDrift reports whether any child resource is missing, was rendered from an outdated spec,
+// or is no longer asked for by the registry spec.
+func (ro *RegistryOperations) CheckRegistryDrift(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	l := log.FromContext(ctx)
+	l.Info("Checking for configuration drift of", "registry", registry.Name)
+
+	children, err := ro.desiredChildren(ctx, registry)
+	if err != nil {
+		return false, err
+	}
+	for _, desired := range children {
+		existing, exists, err := ro.getChild(ctx, desired)
+		if err != nil {
+			return false, err
+		}
+		if !exists || !upToDate(existing, desired) {
+			l.Info("Detected configuration drift of", "registry", registry.Name, "kind", ro.kind(desired))
+			return true, nil
+		}
+	}
+
+	for _, stale := range ro.staleChildren(registry) {
+		existing, exists, err := ro.getChild(ctx, stale)
+		if err != nil {
+			return false, err
+		}
+		if exists && metav1.IsControlledBy(existing, registry) {
+			l.Info("Detected configuration drift of", "registry", registry.Name, "kind", ro.kind(stale))
+			return true, nil
+		}
+	}
+
+	drift, err := ro.checkPersistentVolumeClaimDrift(ctx, registry)
+	if err != nil || drift {
+		if drift {
+			l.Info("Detected configuration drift of", "registry", registry.Name, "kind", "PersistentVolumeClaim")
+		}
+		return drift, err
+	}
+
+	drift, err = ro.checkHtpasswdDrift(ctx, registry)
+	if err != nil || drift {
+		if drift {
+			l.Info("Detected configuration drift of", "registry", registry.Name, "kind", "Secret")
+		}
+		return drift, err
+	}
+
+	drift, err = ro.checkHTTPSecretDrift(ctx, registry)
+	if err != nil || drift {
+		if drift {
+			l.Info("Detected configuration drift of", "registry", registry.Name, "kind", "Secret")
+		}
+		return drift, err
+	}
+
+	drift, err = ro.checkRedisDrift(ctx, registry)
+	if err != nil || drift {
+		if drift {
+			l.Info("Detected configuration drift of", "registry", registry.Name, "kind", "Secret")
+		}
+		return drift, err
+	}
+
+	drift, err = ro.checkNotificationsDrift(ctx, registry)
+	if err != nil || drift {
+		if drift {
+			l.Info("Detected configuration drift of", "registry", registry.Name, "kind", "Secret")
+		}
+		return drift, err
+	}
+
+	drift, err = ro.checkCertificateDrift(ctx, registry)
+	if err != nil || drift {
+		if drift {
+			l.Info("Detected certificate drift of", "registry", registry.Name)
+		}
+		return drift, err
+	}
+
+	drift, err = ro.checkDistributionDrift(ctx, registry)
+	if drift {
+		l.Info("Detected distribution drift of", "registry", registry.Name)
+	}
+	return drift, err
+}
+
+// ApplyRegistryChanges brings the child resources of the registry in line with its spec.
+// Missing resources are created, resources rendered from an outdated spec are replaced
+// and exposure resources that are no longer asked for are deleted.
+func (ro *RegistryOperations) ApplyRegistryChanges(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+
+	// The claim and the generated Secrets and certificates are applied first, the Deployments mount them.
+	if err := ro.applyPersistentVolumeClaim(ctx, registry); err != nil {
+		return err
+	}
+	if err := ro.applyHtpasswdSecret(ctx, registry); err != nil {
+		return err
+	}
+	if err := ro.applyCertificates(ctx, registry); err != nil {
+		return err
+	}
+	if err := ro.applyHTTPSecret(ctx, registry); err != nil {
+		return err
+	}
+	if err := ro.applyRedisPasswordSecret(ctx, registry); err != nil {
+		return err
+	}
+	if err := ro.applyNotificationsSecret(ctx, registry); err != nil {
+		return err
+	}
+
+	children, err := ro.desiredChildren(ctx, registry)
+	if err != nil {
+		return err
+	}
+	for _, desired := range children {
+		existing, exists, err := ro.getChild(ctx, desired)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			l.Info("Creating "+ro.kind(desired)+" for", "registry", registry.Name)
+			err = ro.Client.Create(ctx, desired)
+			if meta.IsNoMatchError(err) {
+				return fmt.Errorf("%s is not installed in the cluster: %w", ro.kind(desired), err)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if upToDate(existing, desired) {
+			continue
+		}
+		l.Info("Updating "+ro.kind(desired)+" for", "registry", registry.Name)
+		desired.SetResourceVersion(existing.GetResourceVersion())
+		desired.SetFinalizers(existing.GetFinalizers())
+		if err := ro.Client.Update(ctx, desired); err != nil {
+			return err
+		}
+	}
+
+	for _, stale := range ro.staleChildren(registry) {
+		existing, exists, err := ro.getChild(ctx, stale)
+		if err != nil {
+			return err
+		}
+		if exists && metav1.IsControlledBy(existing, registry) {
+			l.Info("Deleting "+ro.kind(stale)+" for", "registry", registry.Name)
+			if err := client.IgnoreNotFound(ro.Client.Delete(ctx, stale)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The copies in other namespaces go last, they are rendered from the CA and credentials applied above.
+	return ro.applyDistribution(ctx, registry)
+}
+
+// checkPersistentVolumeClaimDrift reports whether the claim of a filesystem registry is missing
+// or requests less storage than the spec asks for.
+func (ro *RegistryOperations) checkPersistentVolumeClaimDrift(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	if registry.Spec.Storage.Type != registryoperatordevv1alpha1.StorageTypeFilesystem {
+		return false, nil
+	}
+	desired, err := ro.PersistentVolumeClaimFactory.NewPersistentVolumeClaim(registry)
+	if err != nil {
+		return false, err
+	}
+	existing, exists, err := ro.getChild(ctx, desired)
+	if err != nil || !exists {
+		return !exists, err
+	}
+	return needsExpansion(existing.(*apiv1.PersistentVolumeClaim), desired), nil
+}
+
+// applyPersistentVolumeClaim creates the claim of a filesystem registry or expands it to the requested size.
+// Claims are never shrunk, and claims of registries that moved to another storage type are kept
+// until the registry is deleted, so that no data is lost by a spec change.
+func (ro *RegistryOperations) applyPersistentVolumeClaim(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	if registry.Spec.Storage.Type != registryoperatordevv1alpha1.StorageTypeFilesystem {
+		return nil
+	}
+
+	desired, err := ro.PersistentVolumeClaimFactory.NewPersistentVolumeClaim(registry)
+	if err != nil {
+		return err
+	}
+	existing, exists, err := ro.getChild(ctx, desired)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ro.CreateRegistryPersistentVolumeClaim(ctx, registry)
+	}
+
+	pvc := existing.(*apiv1.PersistentVolumeClaim)
+	if !needsExpansion(pvc, desired) {
+		return nil
+	}
+	l.Info("Expanding PersistentVolumeClaim for", "registry", registry.Name)
+	if pvc.Spec.Resources.Requests == nil {
+		pvc.Spec.Resources.Requests = apiv1.ResourceList{}
+	}
+	pvc.Spec.Resources.Requests[apiv1.ResourceStorage] = desired.Spec.Resources.Requests[apiv1.ResourceStorage]
+	return ro.Client.Update(ctx, pvc)
+}
+
+// needsExpansion reports whether the claim requests less storage than the desired claim.
+func needsExpansion(pvc, desired *apiv1.PersistentVolumeClaim) bool {
+	current := pvc.Spec.Resources.Requests[apiv1.ResourceStorage]
+	return current.Cmp(desired.Spec.Resources.Requests[apiv1.ResourceStorage]) < 0
+}
@@ -0,0 +1,235 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// htpasswdEnabled reports whether the registry authenticates clients against an htpasswd file.
+func htpasswdEnabled(registry *registryoperatordevv1alpha1.Registry) bool {
+	return registry.Spec.Auth != nil && registry.Spec.Auth.Htpasswd != nil
+}
+
+// htpasswdCredentials returns the username to password mapping the htpasswd file of the registry is generated from.
+// It reports false when the credentials were to be generated by the operator and do not exist yet.
+func (ro *RegistryOperations) htpasswdCredentials(
+	ctx context.Context,
+	registry *registryoperatordevv1alpha1.Registry,
+) (map[string]string, bool, error) {
+	ref := registry.Spec.Auth.Htpasswd.CredentialsSecretRef
+	name := factories.AdminCredentialsSecretName(registry)
+	if ref != nil {
+		name = ref.Name
+	}
+
+	secret := &apiv1.Secret{}
+	err := ro.Client.Get(ctx, client.ObjectKey{Namespace: registry.Namespace, Name: name}, secret)
+	if err != nil {
+		if apierrors.IsNotFound(err) && ref == nil {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get the credentials Secret %s: %w", name, err)
+	}
+
+	if ref == nil {
+		username := string(secret.Data[apiv1.BasicAuthUsernameKey])
+		return map[string]string{username: string(secret.Data[apiv1.BasicAuthPasswordKey])}, true, nil
+	}
+	credentials := make(map[string]string, len(secret.Data))
+	for username, password := range secret.Data {
+		credentials[username] = string(password)
+	}
+	return credentials, true, nil
+}
+
+// checkHtpasswdDrift reports whether the htpasswd Secret of the registry is missing
+// or was generated from outdated credentials.
+func (ro *RegistryOperations) checkHtpasswdDrift(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	if !htpasswdEnabled(registry) {
+		return false, nil
+	}
+	credentials, found, err := ro.htpasswdCredentials(ctx, registry)
+	if err != nil || !found {
+		return !found, err
+	}
+	err = ro.addRegistryUserCredentials(ctx, registry, credentials)
+	if err != nil {
+		return false, err
+	}
+	return ro.htpasswdOutdated(ctx, registry, credentials)
+}
+
+// addRegistryUserCredentials adds the credentials of the RegistryUsers referencing the registry.
+// Expired users and users whose Secret was not generated yet are left out,
+// as are users whose username is already taken.
+func (ro *RegistryOperations) addRegistryUserCredentials(
+	ctx context.Context,
+	registry *registryoperatordevv1alpha1.Registry,
+	credentials map[string]string,
+) error {
+	users := &registryoperatordevv1alpha1.RegistryUserList{}
+	err := ro.Client.List(ctx, users, client.InNamespace(registry.Namespace))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, user := range users.Items {
+		if user.Spec.RegistryRef.Name != registry.Name {
+			continue
+		}
+		if user.Spec.ExpiresAt != nil && !now.Before(user.Spec.ExpiresAt.Time) {
+			continue
+		}
+		username := user.EffectiveUsername()
+		if _, taken := credentials[username]; taken {
+			continue
+		}
+
+		secret := &apiv1.Secret{}
+		err = ro.Client.Get(ctx, client.ObjectKey{Namespace: user.Namespace, Name: factories.RegistryUserSecretName(&user)}, secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if password := secret.Data[apiv1.BasicAuthPasswordKey]; len(password) > 0 {
+			credentials[username] = string(password)
+		}
+	}
+	return nil
+}
+
+// htpasswdOutdated reports whether the htpasswd Secret of the registry is missing
+// or was generated from other credentials than the given ones.
+func (ro *RegistryOperations) htpasswdOutdated(
+	ctx context.Context,
+	registry *registryoperatordevv1alpha1.Registry,
+	credentials map[string]string,
+) (bool, error) {
+	secret := &apiv1.Secret{}
+	err := ro.Client.Get(ctx, client.ObjectKey{Namespace: registry.Namespace, Name: factories.HtpasswdSecretName(registry)}, secret)
+	if err != nil {
+		return apierrors.IsNotFound(err), client.IgnoreNotFound(err)
+	}
+	key := secret.Data[factories.HtpasswdHMACKeySecretKey]
+	if len(key) == 0 {
+		return true, nil
+	}
+	hash, err := factories.HMAC(key, credentials)
+	if err != nil {
+		return false, err
+	}
+	return secret.Annotations[internal.ConfigHashAnnotation] != hash, nil
+}
+
+// applyHtpasswdSecret generates the htpasswd Secret of the registry from its credentials,
+// creating random admin credentials when no credentials Secret is referenced.
+// The bcrypt entries are only regenerated when the credentials change.
+func (ro *RegistryOperations) applyHtpasswdSecret(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	if !htpasswdEnabled(registry) {
+		return nil
+	}
+
+	credentials, found, err := ro.htpasswdCredentials(ctx, registry)
+	if err != nil {
+		return err
+	}
+	if !found {
+		admin, err := ro.SecretFactory.NewAdminCredentialsSecret(registry)
+		if err != nil {
+			return err
+		}
+		l.Info("Creating admin credentials Secret for", "registry", registry.Name)
+		err = ro.Client.Create(ctx, admin)
+		if err != nil {
+			return err
+		}
+		credentials = map[string]string{
+			admin.StringData[apiv1.BasicAuthUsernameKey]: admin.StringData[apiv1.BasicAuthPasswordKey],
+		}
+	}
+	err = ro.addRegistryUserCredentials(ctx, registry, credentials)
+	if err != nil {
+		return err
+	}
+
+	outdated, err := ro.htpasswdOutdated(ctx, registry, credentials)
+	if err != nil || !outdated {
+		return err
+	}
+
+	desired, err := ro.SecretFactory.NewHtpasswdSecret(registry, credentials)
+	if err != nil {
+		return err
+	}
+	existing, exists, err := ro.getChild(ctx, desired)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		l.Info("Creating htpasswd Secret for", "registry", registry.Name)
+		return ro.Client.Create(ctx, desired)
+	}
+	l.Info("Updating htpasswd Secret for", "registry", registry.Name)
+	desired.SetResourceVersion(existing.GetResourceVersion())
+	return ro.Client.Update(ctx, desired)
+}
+
+// setSecretsHash annotates the pod template with the hash of the versions of the Secrets it consumes.
+// The registry reads them only on startup, so the pods are rolled whenever one of them changes.
+// Only the UID and resource version are hashed, the data must not be derivable from the annotation.
+// Secrets that do not exist yet are left out, their creation changes the hash.
+func (ro *RegistryOperations) setSecretsHash(ctx context.Context, namespace string, template *apiv1.PodTemplateSpec) error {
+	var names []string
+	for _, volume := range template.Spec.Volumes {
+		if volume.Secret != nil {
+			names = append(names, volume.Secret.SecretName)
+		}
+	}
+	for _, container := range template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				names = append(names, env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	versions := map[string]string{}
+	for _, name := range names {
+		secret := &apiv1.Secret{}
+		err := ro.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		versions[name] = string(secret.UID) + "/" + secret.ResourceVersion
+	}
+
+	hash, err := factories.Hash(versions)
+	if err != nil {
+		return err
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[internal.SecretsHashAnnotation] = hash
+	return nil
+}
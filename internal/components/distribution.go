@@ -0,0 +1,295 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// distributionCA returns the CA bundle distributed for the registry, or an empty string if there is none.
+// The CA generated for a self-signed registry is only reported once it exists.
+func (ro *RegistryOperations) distributionCA(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (string, error) {
+	ref := registry.Spec.Distribution.CAConfigMapRef
+	var name string
+	switch {
+	case ref != nil:
+		name = ref.Name
+	case selfSignedTLS(registry):
+		name = factories.CAConfigMapName(registry)
+	default:
+		return "", nil
+	}
+
+	configMap := &apiv1.ConfigMap{}
+	err := ro.Client.Get(ctx, client.ObjectKey{Namespace: registry.Namespace, Name: name}, configMap)
+	if err != nil {
+		if apierrors.IsNotFound(err) && ref == nil {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get the CA ConfigMap %s: %w", name, err)
+	}
+	ca, ok := configMap.Data[factories.CACertificateKey]
+	if !ok {
+		return "", fmt.Errorf("the CA ConfigMap %s has no %s key", name, factories.CACertificateKey)
+	}
+	return ca, nil
+}
+
+// distributionCredentials returns the username and password distributed for the registry,
+// or an empty username if there are none.
+// The admin credentials generated for an htpasswd registry are only reported once they exist.
+func (ro *RegistryOperations) distributionCredentials(
+	ctx context.Context,
+	registry *registryoperatordevv1alpha1.Registry,
+) (string, string, error) {
+	ref := registry.Spec.Distribution.CredentialsSecretRef
+	var name string
+	switch {
+	case ref != nil:
+		name = ref.Name
+	case htpasswdEnabled(registry) && registry.Spec.Auth.Htpasswd.CredentialsSecretRef == nil:
+		name = factories.AdminCredentialsSecretName(registry)
+	default:
+		return "", "", nil
+	}
+
+	secret := &apiv1.Secret{}
+	err := ro.Client.Get(ctx, client.ObjectKey{Namespace: registry.Namespace, Name: name}, secret)
+	if err != nil {
+		if apierrors.IsNotFound(err) && ref == nil {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("failed to get the credentials Secret %s: %w", name, err)
+	}
+	username := string(secret.Data[apiv1.BasicAuthUsernameKey])
+	if username == "" {
+		return "", "", fmt.Errorf("the credentials Secret %s has no %s key", name, apiv1.BasicAuthUsernameKey)
+	}
+	return username, string(secret.Data[apiv1.BasicAuthPasswordKey]), nil
+}
+
+// distributionNamespaces returns the names of the namespaces selected for the distribution of the registry.
+// Only namespaces opted in with the distribution label are selected, an empty selector selects none.
+// System namespaces are skipped, and so are terminating namespaces, nothing can be created in them.
+func (ro *RegistryOperations) distributionNamespaces(
+	ctx context.Context,
+	registry *registryoperatordevv1alpha1.Registry,
+) ([]string, error) {
+	namespaceSelector := registry.Spec.Distribution.NamespaceSelector.DeepCopy()
+	if len(namespaceSelector.MatchLabels) == 0 && len(namespaceSelector.MatchExpressions) == 0 {
+		return nil, nil
+	}
+	namespaceSelector.MatchExpressions = append(namespaceSelector.MatchExpressions, metav1.LabelSelectorRequirement{
+		Key:      registryoperatordevv1alpha1.DistributionLabel,
+		Operator: metav1.LabelSelectorOpIn,
+		Values:   []string{"enabled"},
+	})
+	selector, err := metav1.LabelSelectorAsSelector(namespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace selector: %w", err)
+	}
+	namespaces := &apiv1.NamespaceList{}
+	if err := ro.Client.List(ctx, namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(namespaces.Items))
+	for _, namespace := range namespaces.Items {
+		if namespace.Status.Phase != apiv1.NamespaceTerminating && !strings.HasPrefix(namespace.Name, "kube-") {
+			names = append(names, namespace.Name)
+		}
+	}
+	return names, nil
+}
+
+// desiredDistribution renders the CA bundles and pull secrets the distribution of the registry asks for.
+func (ro *RegistryOperations) desiredDistribution(
+	ctx context.Context,
+	registry *registryoperatordevv1alpha1.Registry,
+) ([]client.Object, error) {
+	if registry.Spec.Distribution == nil {
+		return nil, nil
+	}
+	ca, err := ro.distributionCA(ctx, registry)
+	if err != nil {
+		return nil, err
+	}
+	username, password, err := ro.distributionCredentials(ctx, registry)
+	if err != nil {
+		return nil, err
+	}
+	namespaces, err := ro.distributionNamespaces(ctx, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	var desired []client.Object
+	for _, namespace := range namespaces {
+		if ca != "" {
+			configMap, err := ro.ConfigMapFactory.NewCABundleConfigMap(registry, namespace, ca)
+			if err != nil {
+				return nil, err
+			}
+			desired = append(desired, configMap)
+		}
+		if username != "" {
+			secret, err := ro.SecretFactory.NewPullSecret(registry, namespace, username, password)
+			if err != nil {
+				return nil, err
+			}
+			desired = append(desired, secret)
+		}
+	}
+	return desired, nil
+}
+
+// distributedCopies returns the CA bundles and pull secrets currently distributed for the registry.
+func (ro *RegistryOperations) distributedCopies(
+	ctx context.Context,
+	registry *registryoperatordevv1alpha1.Registry,
+) ([]client.Object, error) {
+	labels := client.MatchingLabels(factories.DistributionLabels(registry))
+
+	configMaps := &apiv1.ConfigMapList{}
+	if err := ro.Client.List(ctx, configMaps, labels); err != nil {
+		return nil, err
+	}
+	secrets := &apiv1.SecretList{}
+	if err := ro.Client.List(ctx, secrets, labels); err != nil {
+		return nil, err
+	}
+
+	copies := make([]client.Object, 0, len(configMaps.Items)+len(secrets.Items))
+	for i := range configMaps.Items {
+		copies = append(copies, &configMaps.Items[i])
+	}
+	for i := range secrets.Items {
+		copies = append(copies, &secrets.Items[i])
+	}
+	return copies, nil
+}
+
+// distributedBy reports whether the object is a copy the operator distributed for the registry.
+// Resources of users that happen to share the name of a copy are left alone.
+func distributedBy(obj client.Object, registry *registryoperatordevv1alpha1.Registry) bool {
+	labels := obj.GetLabels()
+	return labels["registry"] == registry.Name && labels[internal.RegistryNamespaceLabel] == registry.Namespace
+}
+
+// distributionKey identifies a copy across namespaces and kinds.
+func (ro *RegistryOperations) distributionKey(obj client.Object) string {
+	return ro.kind(obj) + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// checkDistributionDrift reports whether a copy of the registry is missing, outdated,
+// or kept in a namespace that is no longer selected.
+func (ro *RegistryOperations) checkDistributionDrift(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	desired, err := ro.desiredDistribution(ctx, registry)
+	if err != nil {
+		return false, err
+	}
+	wanted := make(map[string]bool, len(desired))
+	for _, obj := range desired {
+		wanted[ro.distributionKey(obj)] = true
+		existing, exists, err := ro.getChild(ctx, obj)
+		if err != nil {
+			return false, err
+		}
+		if !exists || (distributedBy(existing, registry) && !upToDate(existing, obj)) {
+			return true, nil
+		}
+	}
+
+	copies, err := ro.distributedCopies(ctx, registry)
+	if err != nil {
+		return false, err
+	}
+	for _, obj := range copies {
+		if !wanted[ro.distributionKey(obj)] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// applyDistribution creates and updates the copies of the registry in the selected namespaces
+// and deletes the ones kept in namespaces that are no longer selected.
+func (ro *RegistryOperations) applyDistribution(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+
+	desired, err := ro.desiredDistribution(ctx, registry)
+	if err != nil {
+		return err
+	}
+	wanted := make(map[string]bool, len(desired))
+	for _, obj := range desired {
+		wanted[ro.distributionKey(obj)] = true
+		existing, exists, err := ro.getChild(ctx, obj)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			l.Info("Creating "+ro.kind(obj)+" for", "registry", registry.Name, "namespace", obj.GetNamespace())
+			if err := ro.Client.Create(ctx, obj); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !distributedBy(existing, registry) {
+			l.Info("Skipping "+ro.kind(obj)+" not distributed by the operator for",
+				"registry", registry.Name, "namespace", obj.GetNamespace(), "name", obj.GetName())
+			continue
+		}
+		if upToDate(existing, obj) {
+			continue
+		}
+		l.Info("Updating "+ro.kind(obj)+" for", "registry", registry.Name, "namespace", obj.GetNamespace())
+		obj.SetResourceVersion(existing.GetResourceVersion())
+		if err := ro.Client.Update(ctx, obj); err != nil {
+			return err
+		}
+	}
+
+	copies, err := ro.distributedCopies(ctx, registry)
+	if err != nil {
+		return err
+	}
+	for _, obj := range copies {
+		if wanted[ro.distributionKey(obj)] {
+			continue
+		}
+		l.Info("Deleting "+ro.kind(obj)+" for", "registry", registry.Name, "namespace", obj.GetNamespace())
+		if err := client.IgnoreNotFound(ro.Client.Delete(ctx, obj)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteDistributedCopies deletes the CA bundles and pull secrets distributed for the registry.
+func (ro *RegistryOperations) DeleteDistributedCopies(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	copies, err := ro.distributedCopies(ctx, registry)
+	if err != nil {
+		return err
+	}
+	for _, obj := range copies {
+		l.Info("Deleting "+ro.kind(obj)+" for", "registry", registry.Name, "namespace", obj.GetNamespace())
+		if err := client.IgnoreNotFound(ro.Client.Delete(ctx, obj)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
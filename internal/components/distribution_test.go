@@ -0,0 +1,77 @@
+package components
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+)
+
+func TestDistributionNamespaces(t *testing.T) {
+	namespace := func(name string, phase apiv1.NamespacePhase, labels map[string]string) client.Object {
+		return &apiv1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Status:     apiv1.NamespaceStatus{Phase: phase},
+		}
+	}
+	selected := map[string]string{"team": "a", registryoperatordevv1alpha1.DistributionLabel: "enabled"}
+	ro := newTestRegistryOperations(t,
+		namespace("team-a", apiv1.NamespaceActive, selected),
+		namespace("team-a-dev", apiv1.NamespaceActive, map[string]string{"team": "a"}),
+		namespace("team-a-old", apiv1.NamespaceActive, map[string]string{
+			"team": "a", registryoperatordevv1alpha1.DistributionLabel: "disabled",
+		}),
+		namespace("team-a-gone", apiv1.NamespaceTerminating, selected),
+		namespace("team-b", apiv1.NamespaceActive, map[string]string{
+			"team": "b", registryoperatordevv1alpha1.DistributionLabel: "enabled",
+		}),
+		namespace("kube-system", apiv1.NamespaceActive, selected),
+		namespace("kube-public", apiv1.NamespaceActive, selected),
+	)
+
+	tests := []struct {
+		name     string
+		selector metav1.LabelSelector
+		want     []string
+	}{
+		{
+			name:     "opted in namespaces",
+			selector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+			want:     []string{"team-a"},
+		},
+		{
+			name: "selector on the opt-in label",
+			selector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      registryoperatordevv1alpha1.DistributionLabel,
+				Operator: metav1.LabelSelectorOpExists,
+			}}},
+			want: []string{"team-a", "team-b"},
+		},
+		{
+			name: "empty selector",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := &registryoperatordevv1alpha1.Registry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "registry"},
+				Spec: registryoperatordevv1alpha1.RegistrySpec{
+					Distribution: &registryoperatordevv1alpha1.RegistryDistribution{NamespaceSelector: tt.selector},
+				},
+			}
+			got, err := ro.distributionNamespaces(context.Background(), registry)
+			if err != nil {
+				t.Fatalf("distributionNamespaces() error = %v", err)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("distributionNamespaces() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package factories
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	apiv1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// CACertificateKey is the key of the CA certificate in the certificate Secrets and the CA ConfigMap.
+	CACertificateKey = "ca.crt"
+	// NextCACertificateKey and NextCAKeyKey are the keys of the renewed CA in the CA Secret,
+	// published next to the current CA before it signs the serving certificate.
+	NextCACertificateKey = "next.crt"
+	NextCAKeyKey         = "next.key"
+	// PreviousCACertificateKey is the key of the replaced CA in the CA Secret, still published for a while.
+	PreviousCACertificateKey = "previous.crt"
+
+	// caValidity is how long a generated CA certificate is valid.
+	caValidity = 10 * 365 * 24 * time.Hour
+	// servingCertificateValidity is how long a generated serving certificate is valid.
+	servingCertificateValidity = 90 * 24 * time.Hour
+)
+
+// TLSSecretName returns the name of the Secret holding the serving certificate of the registry.
+func TLSSecretName(registry *registryoperatordevv1alpha1.Registry) string {
+	if tls := registry.Spec.TLS; tls != nil && tls.SecretName != "" {
+		return tls.SecretName
+	}
+	return registry.Name + "-tls"
+}
+
+// CASecretName returns the name of the Secret holding the generated CA of the registry.
+func CASecretName(registry *registryoperatordevv1alpha1.Registry) string {
+	return registry.Name + "-ca"
+}
+
+// CAConfigMapName returns the name of the ConfigMap publishing the generated CA certificate of the registry.
+func CAConfigMapName(registry *registryoperatordevv1alpha1.Registry) string {
+	return registry.Name + "-ca"
+}
+
+// DNSNames returns the names the serving certificate of the registry is valid for.
+func (f *SecretFactory) DNSNames(registry *registryoperatordevv1alpha1.Registry) []string {
+	names := []string{
+		registry.Name,
+		registry.Name + "." + registry.Namespace,
+		registry.Name + "." + registry.Namespace + ".svc",
+		registry.Name + "." + registry.Namespace + ".svc.cluster.local",
+	}
+	if exposure := registry.Spec.Exposure; exposure != nil && !slices.Contains(names, exposure.Host) {
+		names = append(names, exposure.Host)
+	}
+	return names
+}
+
+// NewCASecret creates a kubernetes.io/tls Secret holding a new self-signed CA for the registry.
+func (f *SecretFactory) NewCASecret(registry *registryoperatordevv1alpha1.Registry) (*apiv1.Secret, error) {
+	now := time.Now()
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: registry.Namespace + "/" + registry.Name + " registry CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certificate, key, err := issueCertificate(template, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.newCertificateSecret(registry, CASecretName(registry), certificate, key, certificate), nil
+}
+
+// NewServingCertificateSecret creates a kubernetes.io/tls Secret holding a new serving certificate
+// for the registry, signed by the CA in the given Secret.
+func (f *SecretFactory) NewServingCertificateSecret(
+	registry *registryoperatordevv1alpha1.Registry,
+	ca *apiv1.Secret,
+) (*apiv1.Secret, error) {
+	caCertificate, err := ParseCertificate(ca.Data[apiv1.TLSCertKey])
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(ca.Data[apiv1.TLSPrivateKeyKey])
+	if block == nil {
+		return nil, errors.New("CA key is not PEM encoded")
+	}
+	caKey, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the CA key: %w", err)
+	}
+
+	now := time.Now()
+	dnsNames := f.DNSNames(registry)
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:    dnsNames,
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(servingCertificateValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certificate, key, err := issueCertificate(template, caCertificate, caKey)
+	if err != nil {
+		return nil, err
+	}
+	return f.newCertificateSecret(registry, TLSSecretName(registry), certificate, key, ca.Data[apiv1.TLSCertKey]), nil
+}
+
+// newCertificateSecret creates a kubernetes.io/tls Secret holding the PEM encoded certificate, key and CA.
+func (f *SecretFactory) newCertificateSecret(
+	registry *registryoperatordevv1alpha1.Registry,
+	name string,
+	certificate, key, ca []byte,
+) *apiv1.Secret {
+	return &apiv1.Secret{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            name,
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+		},
+		Type: apiv1.SecretTypeTLS,
+		Data: map[string][]byte{
+			apiv1.TLSCertKey:       certificate,
+			apiv1.TLSPrivateKeyKey: key,
+			CACertificateKey:       ca,
+		},
+	}
+}
+
+// issueCertificate generates a key and a certificate for it from the template, signed by the parent.
+// The certificate is self-signed when no parent is given. Both are returned PEM encoded.
+func issueCertificate(template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.SerialNumber, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		nil
+}
+
+// ParseCertificate parses the first certificate of a PEM bundle.
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("certificate is not PEM encoded")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// NewCAConfigMap creates a Kubernetes ConfigMap publishing the CA bundle of the registry.
+func (f *ConfigMapFactory) NewCAConfigMap(registry *registryoperatordevv1alpha1.Registry, ca []byte) *apiv1.ConfigMap {
+	return &apiv1.ConfigMap{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            CAConfigMapName(registry),
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+		},
+		Data: map[string]string{
+			CACertificateKey: string(ca),
+		},
+	}
+}
@@ -4,30 +4,263 @@ import (
 	"fmt"
 
 	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/distribution"
 	apiv1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
-type ConfigMapFactory struct{}
+// TokenRootCertBundleKey is the key of the token signing certificate in the registry ConfigMap.
+const TokenRootCertBundleKey = "token-rootcertbundle.pem"
 
-func NewConfigMapFactory() *ConfigMapFactory {
-	return &ConfigMapFactory{}
+// TokenAuthOptions configures how registries with token authentication reach the token issuer of the operator.
+type TokenAuthOptions struct {
+	// Realm is the URL of the token endpoint clients are redirected to.
+	Realm string
+	// Issuer is the name the tokens are signed with.
+	Issuer string
+	// RootCertBundle is the PEM encoded certificate the tokens are verified with.
+	RootCertBundle []byte
 }
 
-// CreatePod creates a Kubernetes Pod based on the registry specification.
-func (f *ConfigMapFactory) NewConfigMap(registry *registryoperatordevv1alpha1.Registry) *apiv1.ConfigMap {
-	return &apiv1.ConfigMap{
+type ConfigMapFactory struct {
+	// TokenAuth is nil when the token issuer of the operator is disabled.
+	TokenAuth *TokenAuthOptions
+}
+
+func NewConfigMapFactory(tokenAuth *TokenAuthOptions) *ConfigMapFactory {
+	return &ConfigMapFactory{TokenAuth: tokenAuth}
+}
+
+// ReadOnly reports whether the registry is configured to reject pushes and deletes,
+// either on request of the user or while garbage collection runs against its storage.
+// Blobs uploaded during garbage collection could be removed before they are referenced.
+func ReadOnly(registry *registryoperatordevv1alpha1.Registry) bool {
+	maintenance := registry.Spec.Maintenance
+	return (maintenance != nil && maintenance.ReadOnly) || registry.Status.GarbageCollectionJob != ""
+}
+
+// NewConfigMap creates a Kubernetes ConfigMap holding the registry configuration.
+func (f *ConfigMapFactory) NewConfigMap(registry *registryoperatordevv1alpha1.Registry) (*apiv1.ConfigMap, error) {
+	if registry.Spec.Auth != nil && registry.Spec.Auth.Token != nil && f.TokenAuth == nil {
+		return nil, fmt.Errorf("token authentication is not enabled in the operator")
+	}
+	config, err := f.NewConfiguration(registry).Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	configMap := &apiv1.ConfigMap{
 		ObjectMeta: ctrl.ObjectMeta{
-			Name:      registry.Name,
-			Namespace: registry.Namespace,
+			Name:            registry.Name,
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
 			Labels: map[string]string{
 				"app":      "registry",
 				"registry": registry.Name,
 			},
 		},
-		// We could use Configuration struct from registry repo in the future.
 		Data: map[string]string{
-			"config.yml": fmt.Sprintf("version: 0.1\nstorage:\n\t%s:\n", registry.Spec.Storage.Type),
+			"config.yml": string(config),
+		},
+	}
+	if registry.Spec.Auth != nil && registry.Spec.Auth.Token != nil {
+		configMap.Data[TokenRootCertBundleKey] = string(f.TokenAuth.RootCertBundle)
+	}
+	if err := setConfigHash(configMap, configMap.Data); err != nil {
+		return nil, err
+	}
+	return configMap, nil
+}
+
+// NewConfiguration creates the distribution configuration based on the registry specification.
+func (f *ConfigMapFactory) NewConfiguration(registry *registryoperatordevv1alpha1.Registry) *distribution.Configuration {
+	config := &distribution.Configuration{
+		Version: distribution.Version,
+		Storage: f.storageConfig(registry),
+		Auth:    f.authConfig(registry),
+		Proxy:   f.proxyConfig(registry),
+		Redis:   f.redisConfig(registry),
+		HTTP: distribution.HTTP{
+			Addr: fmt.Sprintf(":%d", registryPort),
+			Headers: map[string][]string{
+				"X-Content-Type-Options": {"nosniff"},
+			},
 		},
+		Health: &distribution.Health{
+			StorageDriver: &distribution.StorageDriverHealth{
+				Enabled:   true,
+				Interval:  "10s",
+				Threshold: 3,
+			},
+		},
+	}
+	config.Notifications = f.notificationsConfig(registry)
+	if ReadOnly(registry) {
+		if config.Storage.Maintenance == nil {
+			config.Storage.Maintenance = &distribution.StorageMaintenance{}
+		}
+		config.Storage.Maintenance.ReadOnly = &distribution.ReadOnly{Enabled: true}
+	}
+	if registry.Spec.TLS != nil {
+		config.HTTP.TLS = &distribution.TLS{
+			Certificate: tlsPath + "/" + apiv1.TLSCertKey,
+			Key:         tlsPath + "/" + apiv1.TLSPrivateKeyKey,
+		}
+	}
+	return config
+}
+
+// proxyConfig renders the proxy section of the registry configuration.
+// Credentials are not rendered, they are injected into the pod as environment variables.
+func (f *ConfigMapFactory) proxyConfig(registry *registryoperatordevv1alpha1.Registry) *distribution.Proxy {
+	proxy := registry.Spec.Proxy
+	if proxy == nil {
+		return nil
+	}
+	config := &distribution.Proxy{
+		RemoteURL: proxy.RemoteURL,
+	}
+	if proxy.TTL != nil {
+		config.TTL = proxy.TTL.Duration.String()
+	}
+	return config
+}
+
+// redisConfig renders the redis section of the registry configuration.
+// The password is not rendered, it is injected into the pod as an environment variable.
+func (f *ConfigMapFactory) redisConfig(registry *registryoperatordevv1alpha1.Registry) *distribution.Redis {
+	cache := registry.Spec.Cache
+	if cache == nil || cache.Type != registryoperatordevv1alpha1.CacheTypeRedis {
+		return nil
+	}
+	config := &distribution.Redis{
+		Addr: RedisAddr(registry),
+	}
+	if cache.Redis != nil {
+		config.DB = int(cache.Redis.DB)
+	}
+	return config
+}
+
+// authConfig renders the auth section of the registry configuration.
+func (f *ConfigMapFactory) authConfig(registry *registryoperatordevv1alpha1.Registry) *distribution.Auth {
+	auth := registry.Spec.Auth
+	if auth == nil {
+		return nil
+	}
+	if auth.Token != nil && f.TokenAuth != nil {
+		return &distribution.Auth{
+			Token: &distribution.TokenAuth{
+				Realm:          f.TokenAuth.Realm,
+				Service:        registry.Namespace + "/" + registry.Name,
+				Issuer:         f.TokenAuth.Issuer,
+				RootCertBundle: registryConfigPath + "/" + TokenRootCertBundleKey,
+			},
+		}
+	}
+	if auth.Htpasswd == nil {
+		return nil
+	}
+	realm := auth.Htpasswd.Realm
+	if realm == "" {
+		realm = "Registry Realm"
+	}
+	return &distribution.Auth{
+		Htpasswd: &distribution.HtpasswdAuth{
+			Realm: realm,
+			Path:  authPath + "/" + HtpasswdSecretKey,
+		},
+	}
+}
+
+// storageConfig renders the storage section of the registry configuration.
+func (f *ConfigMapFactory) storageConfig(registry *registryoperatordevv1alpha1.Registry) distribution.Storage {
+	var storage distribution.Storage
+	switch registry.Spec.Storage.Type {
+	case registryoperatordevv1alpha1.StorageTypeInMemory:
+		storage.InMemory = &distribution.InMemoryStorage{}
+	case registryoperatordevv1alpha1.StorageTypeFilesystem:
+		storage.Filesystem = &distribution.FilesystemStorage{
+			RootDirectory: registryDataPath,
+		}
+	case registryoperatordevv1alpha1.StorageTypeS3:
+		storage.S3 = f.s3Config(registry.Spec.Storage.S3)
+	case registryoperatordevv1alpha1.StorageTypeGCS:
+		storage.GCS = f.gcsConfig(registry.Spec.Storage.GCS)
+	case registryoperatordevv1alpha1.StorageTypeAzure:
+		storage.Azure = f.azureConfig(registry.Spec.Storage.Azure)
+	}
+	if registry.Spec.Storage.Delete != nil {
+		storage.Delete = &distribution.StorageDelete{Enabled: registry.Spec.Storage.Delete.Enabled}
+	}
+	storage.Maintenance = f.maintenanceConfig(registry.Spec.Storage.Maintenance)
+	if registry.Spec.Cache != nil {
+		storage.Cache = &distribution.StorageCache{BlobDescriptor: string(registry.Spec.Cache.Type)}
+	}
+	return storage
+}
+
+// maintenanceConfig renders the upload purging of the storage.
+// The read-only mode is rendered from the spec and status of the registry, see ReadOnly.
+func (f *ConfigMapFactory) maintenanceConfig(maintenance *registryoperatordevv1alpha1.StorageMaintenance) *distribution.StorageMaintenance {
+	if maintenance == nil || maintenance.UploadPurging == nil {
+		return nil
+	}
+	purging := maintenance.UploadPurging
+	config := &distribution.UploadPurging{
+		Enabled: purging.Enabled,
+		DryRun:  purging.DryRun,
+	}
+	if purging.Age != nil {
+		config.Age = purging.Age.Duration.String()
+	}
+	if purging.Interval != nil {
+		config.Interval = purging.Interval.Duration.String()
+	}
+	return &distribution.StorageMaintenance{UploadPurging: config}
+}
+
+// s3Config renders the parameters of the S3 storage driver.
+// Credentials are not rendered, they are injected into the pod as environment variables.
+func (f *ConfigMapFactory) s3Config(s3 *registryoperatordevv1alpha1.S3Storage) *distribution.S3Storage {
+	if s3 == nil {
+		return nil
+	}
+	return &distribution.S3Storage{
+		Bucket:         s3.Bucket,
+		Region:         s3.Region,
+		RegionEndpoint: s3.RegionEndpoint,
+		RootDirectory:  s3.RootDirectory,
+		Encrypt:        s3.Encrypt,
+		KeyID:          s3.KeyID,
+	}
+}
+
+// gcsConfig renders the parameters of the GCS storage driver.
+// The key file points at the credentials mounted into the pod.
+func (f *ConfigMapFactory) gcsConfig(gcs *registryoperatordevv1alpha1.GCSStorage) *distribution.GCSStorage {
+	if gcs == nil {
+		return nil
+	}
+	config := &distribution.GCSStorage{
+		Bucket:        gcs.Bucket,
+		RootDirectory: gcs.RootDirectory,
+	}
+	if gcs.CredentialsSecretRef != nil {
+		config.KeyFile = gcsCredentialsPath + "/" + GCSKeyFileSecretKey
+	}
+	return config
+}
+
+// azureConfig renders the parameters of the Azure storage driver.
+// The account key is not rendered, it is injected into the pod as an environment variable.
+func (f *ConfigMapFactory) azureConfig(azure *registryoperatordevv1alpha1.AzureStorage) *distribution.AzureStorage {
+	if azure == nil {
+		return nil
+	}
+	return &distribution.AzureStorage{
+		AccountName: azure.AccountName,
+		Container:   azure.Container,
+		Realm:       azure.Realm,
 	}
 }
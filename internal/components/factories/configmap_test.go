@@ -0,0 +1,113 @@
+package factories
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestNewConfigurationGolden(t *testing.T) {
+	credentials := &apiv1.LocalObjectReference{Name: "storage-credentials"}
+	tests := []struct {
+		name    string
+		storage registryoperatordevv1alpha1.Storage
+		proxy   *registryoperatordevv1alpha1.RegistryProxy
+	}{
+		{
+			name: "inmemory",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeInMemory,
+			},
+		},
+		{
+			name: "filesystem",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeFilesystem,
+			},
+		},
+		{
+			name: "s3",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeS3,
+				S3: &registryoperatordevv1alpha1.S3Storage{
+					Bucket:               "registry",
+					Region:               "eu-central-1",
+					RegionEndpoint:       "http://minio.minio.svc:9000",
+					RootDirectory:        "/registry",
+					Encrypt:              true,
+					KeyID:                "key-id",
+					CredentialsSecretRef: credentials,
+				},
+			},
+		},
+		{
+			name: "gcs",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeGCS,
+				GCS: &registryoperatordevv1alpha1.GCSStorage{
+					Bucket:               "registry",
+					RootDirectory:        "/registry",
+					CredentialsSecretRef: credentials,
+				},
+			},
+		},
+		{
+			name: "azure",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeAzure,
+				Azure: &registryoperatordevv1alpha1.AzureStorage{
+					AccountName:          "registry",
+					Container:            "registry",
+					Realm:                "core.windows.net",
+					CredentialsSecretRef: *credentials,
+				},
+			},
+		},
+		{
+			name: "proxy",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeFilesystem,
+			},
+			proxy: &registryoperatordevv1alpha1.RegistryProxy{
+				RemoteURL: "https://registry-1.docker.io",
+				TTL:       &metav1.Duration{Duration: 72 * time.Hour},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := &registryoperatordevv1alpha1.Registry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"},
+				Spec:       registryoperatordevv1alpha1.RegistrySpec{Storage: tt.storage, Proxy: tt.proxy},
+			}
+			got, err := NewConfigMapFactory(nil).NewConfiguration(registry).Marshal()
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".yaml")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("configuration differs from %s, run the test with -update if the change is intended:\n--- got\n%s\n--- want\n%s", golden, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package factories
+
+import (
+	"slices"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+type CronJobFactory struct {
+	PodFactory *PodFactory
+}
+
+func NewCronJobFactory(podFactory *PodFactory) *CronJobFactory {
+	return &CronJobFactory{PodFactory: podFactory}
+}
+
+// GarbageCollectionCronJobName returns the name of the CronJob running garbage collection for the registry.
+func GarbageCollectionCronJobName(registry *registryoperatordevv1alpha1.Registry) string {
+	return registry.Name + "-garbage-collection"
+}
+
+// GarbageCollectionLabels returns the labels of the garbage collection Jobs and pods of the registry.
+// They differ from the registry pod labels, so that the Service never routes to garbage collection pods.
+func GarbageCollectionLabels(registry *registryoperatordevv1alpha1.Registry) map[string]string {
+	return map[string]string{
+		"app":      "registry-garbage-collection",
+		"registry": registry.Name,
+	}
+}
+
+// NewGarbageCollectionCronJob creates a Kubernetes CronJob running garbage collection against the storage
+// of the registry. Its Jobs are created suspended, the operator starts them once the registry is read-only.
+func (f *CronJobFactory) NewGarbageCollectionCronJob(registry *registryoperatordevv1alpha1.Registry) (*batchv1.CronJob, error) {
+	gc := registry.Spec.GarbageCollection
+	template, err := f.PodFactory.NewPodTemplate(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Labels = GarbageCollectionLabels(registry)
+	template.Spec.RestartPolicy = apiv1.RestartPolicyNever
+	container := &template.Spec.Containers[0]
+	container.Name = "garbage-collect"
+	container.Ports = nil
+	container.ReadinessProbe = nil
+	container.LivenessProbe = nil
+	container.Args = []string{"garbage-collect"}
+	if gc.DeleteUntagged {
+		container.Args = append(container.Args, "--delete-untagged")
+	}
+	if gc.DryRun {
+		container.Args = append(container.Args, "--dry-run")
+	}
+	container.Args = append(container.Args, registryConfigPath+"/config.yml")
+
+	// A claim that cannot be shared between nodes is only attachable next to the registry pods.
+	filesystem := registry.Spec.Storage.Filesystem
+	if registry.Spec.Storage.Type == registryoperatordevv1alpha1.StorageTypeFilesystem && filesystem != nil &&
+		!slices.Contains(filesystem.AccessModes, apiv1.ReadWriteMany) {
+		template.Spec.Affinity = &apiv1.Affinity{
+			PodAffinity: &apiv1.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []apiv1.PodAffinityTerm{
+					{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: selectorLabels(registry)},
+						TopologyKey:   apiv1.LabelHostname,
+					},
+				},
+			},
+		}
+	}
+
+	suspend := true
+	backoffLimit := int32(2)
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            GarbageCollectionCronJobName(registry),
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          gc.Schedule,
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: ctrl.ObjectMeta{
+					Labels: GarbageCollectionLabels(registry),
+				},
+				Spec: batchv1.JobSpec{
+					Suspend:      &suspend,
+					BackoffLimit: &backoffLimit,
+					Template:     *template,
+				},
+			},
+		},
+	}
+	if err := setConfigHash(cronJob, cronJob.Spec); err != nil {
+		return nil, err
+	}
+	return cronJob, nil
+}
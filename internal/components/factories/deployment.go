@@ -0,0 +1,86 @@
+package factories
+
+import (
+	"slices"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+type DeploymentFactory struct {
+	PodFactory       *PodFactory
+	ConfigMapFactory *ConfigMapFactory
+}
+
+func NewDeploymentFactory(podFactory *PodFactory, configMapFactory *ConfigMapFactory) *DeploymentFactory {
+	return &DeploymentFactory{
+		PodFactory:       podFactory,
+		ConfigMapFactory: configMapFactory,
+	}
+}
+
+// NewDeployment creates a Kubernetes Deployment running the registry based on the registry specification.
+func (f *DeploymentFactory) NewDeployment(registry *registryoperatordevv1alpha1.Registry) (*appsv1.Deployment, error) {
+	template, err := f.PodFactory.NewPodTemplate(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	// The registry does not reload its configuration, so the pods are rolled whenever it changes.
+	configMap, err := f.ConfigMapFactory.NewConfigMap(registry)
+	if err != nil {
+		return nil, err
+	}
+	template.Annotations = map[string]string{
+		internal.ConfigHashAnnotation: configMap.Annotations[internal.ConfigHashAnnotation],
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            registry.Name,
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: registry.Spec.Replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selectorLabels(registry),
+			},
+			Template: *template,
+			Strategy: f.strategy(registry),
+		},
+	}
+
+	// Replicas are excluded from the hash, scaling is applied without rolling the registry.
+	hashed := deployment.Spec.DeepCopy()
+	hashed.Replicas = nil
+	if err := setConfigHash(deployment, hashed); err != nil {
+		return nil, err
+	}
+	return deployment, nil
+}
+
+// strategy returns the deployment strategy for the registry.
+// Unless the claim can be shared between nodes, it cannot be attached to the old and new pods at once,
+// so registries using filesystem storage are recreated instead of rolled.
+func (f *DeploymentFactory) strategy(registry *registryoperatordevv1alpha1.Registry) appsv1.DeploymentStrategy {
+	filesystem := registry.Spec.Storage.Filesystem
+	if registry.Spec.Storage.Type == registryoperatordevv1alpha1.StorageTypeFilesystem && filesystem != nil &&
+		!slices.Contains(filesystem.AccessModes, apiv1.ReadWriteMany) {
+		return appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	}
+	return appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType}
+}
+
+// SelectorLabels returns the label selector matching the pods of the registry, in string form.
+func SelectorLabels(registry *registryoperatordevv1alpha1.Registry) string {
+	return metav1.FormatLabelSelector(&metav1.LabelSelector{MatchLabels: selectorLabels(registry)})
+}
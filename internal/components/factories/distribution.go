@@ -0,0 +1,74 @@
+package factories
+
+import (
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal"
+	apiv1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// CABundleConfigMapName returns the name of the copies of the registry CA bundle in the selected namespaces.
+func CABundleConfigMapName(registry *registryoperatordevv1alpha1.Registry) string {
+	return registry.Name + "-ca-bundle"
+}
+
+// PullSecretName returns the name of the pull secrets of the registry in the selected namespaces.
+func PullSecretName(registry *registryoperatordevv1alpha1.Registry) string {
+	return registry.Name + "-pull-secret"
+}
+
+// DistributionLabels returns the labels of the resources copied to other namespaces for the registry.
+// Owner references cannot cross namespaces, the labels tie the copies to their registry instead.
+func DistributionLabels(registry *registryoperatordevv1alpha1.Registry) map[string]string {
+	return map[string]string{
+		"app":                           "registry",
+		"registry":                      registry.Name,
+		internal.RegistryNamespaceLabel: registry.Namespace,
+	}
+}
+
+// NewCABundleConfigMap creates a Kubernetes ConfigMap holding the CA bundle of the registry in the given namespace.
+func (f *ConfigMapFactory) NewCABundleConfigMap(
+	registry *registryoperatordevv1alpha1.Registry,
+	namespace string,
+	ca string,
+) (*apiv1.ConfigMap, error) {
+	configMap := &apiv1.ConfigMap{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      CABundleConfigMapName(registry),
+			Namespace: namespace,
+			Labels:    DistributionLabels(registry),
+		},
+		Data: map[string]string{
+			CACertificateKey: ca,
+		},
+	}
+	if err := setConfigHash(configMap, configMap.Data); err != nil {
+		return nil, err
+	}
+	return configMap, nil
+}
+
+// NewPullSecret creates a kubernetes.io/dockerconfigjson Secret in the given namespace
+// with the credentials for every host of the registry.
+func (f *SecretFactory) NewPullSecret(
+	registry *registryoperatordevv1alpha1.Registry,
+	namespace string,
+	username, password string,
+) (*apiv1.Secret, error) {
+	dockerConfig, err := dockerConfigJSON(f.ServiceFactory.Hosts(registry), username, password)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.Secret{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      PullSecretName(registry),
+			Namespace: namespace,
+			Labels:    DistributionLabels(registry),
+		},
+		Type: apiv1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			apiv1.DockerConfigJsonKey: dockerConfig,
+		},
+	}, nil
+}
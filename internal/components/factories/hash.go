@@ -0,0 +1,48 @@
+package factories
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/registry-operator/registry-operator/internal"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Hash returns a stable hex encoded SHA-256 hash of the JSON representation of the given value.
+func Hash(value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// HMAC returns a hex encoded HMAC-SHA256 of the JSON representation of the given value.
+// It is used instead of Hash for values derived from secret data, whose plain hash could be brute forced.
+func HMAC(key []byte, value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// setConfigHash annotates the object with the hash of the desired state it was rendered from.
+func setConfigHash(obj metav1.Object, desired any) error {
+	hash, err := Hash(desired)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[internal.ConfigHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+	return nil
+}
@@ -0,0 +1,73 @@
+package factories
+
+import (
+	"fmt"
+	"maps"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+type HTTPRouteFactory struct {
+	ServiceFactory *ServiceFactory
+}
+
+func NewHTTPRouteFactory(serviceFactory *ServiceFactory) *HTTPRouteFactory {
+	return &HTTPRouteFactory{ServiceFactory: serviceFactory}
+}
+
+// NewHTTPRoute creates a Gateway API HTTPRoute routing the exposure host to the registry Service.
+func (f *HTTPRouteFactory) NewHTTPRoute(registry *registryoperatordevv1alpha1.Registry) (*gatewayv1.HTTPRoute, error) {
+	exposure := registry.Spec.Exposure
+	if exposure == nil || exposure.HTTPRoute == nil {
+		return nil, fmt.Errorf("registry %s is not exposed through an HTTPRoute", registry.Name)
+	}
+
+	parentRefs := make([]gatewayv1.ParentReference, 0, len(exposure.HTTPRoute.ParentRefs))
+	for _, ref := range exposure.HTTPRoute.ParentRefs {
+		parentRefs = append(parentRefs, gatewayv1.ParentReference{
+			Name:        gatewayv1.ObjectName(ref.Name),
+			Namespace:   (*gatewayv1.Namespace)(ref.Namespace),
+			SectionName: (*gatewayv1.SectionName)(ref.SectionName),
+		})
+	}
+
+	port := gatewayv1.PortNumber(f.ServiceFactory.port(registry))
+	route := &gatewayv1.HTTPRoute{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            registry.Name,
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+			Annotations: maps.Clone(exposure.Annotations),
+		},
+		Spec: gatewayv1.HTTPRouteSpec{
+			CommonRouteSpec: gatewayv1.CommonRouteSpec{
+				ParentRefs: parentRefs,
+			},
+			Hostnames: []gatewayv1.Hostname{gatewayv1.Hostname(exposure.Host)},
+			Rules: []gatewayv1.HTTPRouteRule{
+				{
+					BackendRefs: []gatewayv1.HTTPBackendRef{
+						{
+							BackendRef: gatewayv1.BackendRef{
+								BackendObjectReference: gatewayv1.BackendObjectReference{
+									Name: gatewayv1.ObjectName(registry.Name),
+									Port: &port,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := setConfigHash(route, route); err != nil {
+		return nil, err
+	}
+	return route, nil
+}
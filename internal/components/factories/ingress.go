@@ -0,0 +1,103 @@
+package factories
+
+import (
+	"fmt"
+	"maps"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	networkingv1 "k8s.io/api/networking/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// ingressAnnotations allow pushing large image layers through ingress-nginx.
+var ingressAnnotations = map[string]string{
+	"nginx.ingress.kubernetes.io/proxy-body-size":         "0",
+	"nginx.ingress.kubernetes.io/proxy-read-timeout":      "600",
+	"nginx.ingress.kubernetes.io/proxy-send-timeout":      "600",
+	"nginx.ingress.kubernetes.io/proxy-request-buffering": "off",
+}
+
+type IngressFactory struct{}
+
+func NewIngressFactory() *IngressFactory {
+	return &IngressFactory{}
+}
+
+// NewIngress creates a Kubernetes Ingress routing the exposure host to the registry Service.
+func (f *IngressFactory) NewIngress(registry *registryoperatordevv1alpha1.Registry) (*networkingv1.Ingress, error) {
+	exposure := registry.Spec.Exposure
+	if exposure == nil || exposure.Ingress == nil {
+		return nil, fmt.Errorf("registry %s is not exposed through an Ingress", registry.Name)
+	}
+
+	annotations := maps.Clone(ingressAnnotations)
+	if registry.Spec.TLS != nil {
+		annotations["nginx.ingress.kubernetes.io/backend-protocol"] = "HTTPS"
+	}
+	maps.Copy(annotations, exposure.Annotations)
+
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            registry.Name,
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+			Annotations: annotations,
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: exposure.Ingress.ClassName,
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: exposure.Host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     "/",
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: registry.Name,
+											Port: networkingv1.ServiceBackendPort{
+												Name: "http",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if exposure.TLS != nil {
+		ingress.Spec.TLS = []networkingv1.IngressTLS{
+			{
+				Hosts:      []string{exposure.Host},
+				SecretName: exposure.TLS.SecretName,
+			},
+		}
+	}
+	if err := setConfigHash(ingress, ingress); err != nil {
+		return nil, err
+	}
+	return ingress, nil
+}
+
+// ExternalURL returns the URL under which the registry is reachable through its exposure,
+// or an empty string when the registry is not exposed.
+func ExternalURL(registry *registryoperatordevv1alpha1.Registry) string {
+	exposure := registry.Spec.Exposure
+	if exposure == nil {
+		return ""
+	}
+	if exposure.TLS != nil {
+		return "https://" + exposure.Host
+	}
+	return "http://" + exposure.Host
+}
@@ -0,0 +1,139 @@
+package factories
+
+import (
+	"encoding/json"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/distribution"
+	apiv1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// NotificationsSecretKey is the key of the rendered notification endpoints in the generated notifications Secret.
+const NotificationsSecretKey = "endpoints"
+
+// SecretValue returns the value of the Secret key selected by a notification header.
+// It reports false when an optional Secret or key does not exist.
+type SecretValue func(selector *apiv1.SecretKeySelector) (string, bool, error)
+
+// NotificationsSecretName returns the name of the Secret holding the notification endpoints
+// rendered with the header values taken from Secrets.
+func NotificationsSecretName(registry *registryoperatordevv1alpha1.Registry) string {
+	return registry.Name + "-notifications"
+}
+
+// HasSecretHeaders reports whether a header of a notification endpoint is taken from a Secret.
+func HasSecretHeaders(registry *registryoperatordevv1alpha1.Registry) bool {
+	if registry.Spec.Notifications == nil {
+		return false
+	}
+	for _, endpoint := range registry.Spec.Notifications.Endpoints {
+		for _, header := range endpoint.Headers {
+			if header.SecretKeyRef != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// notificationEndpoints renders the notification endpoints of the registry. Headers taken from Secrets
+// are resolved with value, and left out when value is nil.
+func notificationEndpoints(registry *registryoperatordevv1alpha1.Registry, value SecretValue) ([]distribution.Endpoint, error) {
+	endpoints := make([]distribution.Endpoint, 0, len(registry.Spec.Notifications.Endpoints))
+	for _, endpoint := range registry.Spec.Notifications.Endpoints {
+		config := distribution.Endpoint{
+			Name:      endpoint.Name,
+			URL:       endpoint.URL,
+			Threshold: int(endpoint.Threshold),
+		}
+		for _, header := range endpoint.Headers {
+			headerValue := header.Value
+			if header.SecretKeyRef != nil {
+				if value == nil {
+					continue
+				}
+				resolved, found, err := value(header.SecretKeyRef)
+				if err != nil {
+					return nil, err
+				}
+				if !found {
+					continue
+				}
+				headerValue = resolved
+			}
+			if config.Headers == nil {
+				config.Headers = map[string][]string{}
+			}
+			config.Headers[header.Name] = append(config.Headers[header.Name], headerValue)
+		}
+		if endpoint.Timeout != nil {
+			config.Timeout = endpoint.Timeout.Duration.String()
+		}
+		if endpoint.Backoff != nil {
+			config.Backoff = endpoint.Backoff.Duration.String()
+		}
+		if ignore := endpoint.Ignore; ignore != nil {
+			config.Ignore = &distribution.Ignore{MediaTypes: ignore.MediaTypes}
+			for _, action := range ignore.Actions {
+				config.Ignore.Actions = append(config.Ignore.Actions, string(action))
+			}
+		}
+		endpoints = append(endpoints, config)
+	}
+	return endpoints, nil
+}
+
+// notificationsConfig renders the notifications section of the registry configuration.
+// Headers taken from Secrets are not rendered, the endpoints are overridden in the pod with them, see addNotifications.
+func (f *ConfigMapFactory) notificationsConfig(registry *registryoperatordevv1alpha1.Registry) *distribution.Notifications {
+	if registry.Spec.Notifications == nil {
+		return nil
+	}
+	endpoints, _ := notificationEndpoints(registry, nil)
+	return &distribution.Notifications{
+		Endpoints: endpoints,
+	}
+}
+
+// NewNotificationsSecret creates a Kubernetes Secret holding the notification endpoints of the registry
+// with the header values taken from Secrets, resolved with value.
+// The endpoints are rendered as JSON, so that the values need no escaping whatever they contain.
+func (f *SecretFactory) NewNotificationsSecret(
+	registry *registryoperatordevv1alpha1.Registry,
+	value SecretValue,
+) (*apiv1.Secret, error) {
+	endpoints, err := notificationEndpoints(registry, value)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.Secret{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            NotificationsSecretName(registry),
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+		},
+		Data: map[string][]byte{
+			NotificationsSecretKey: data,
+		},
+	}, nil
+}
+
+// addNotifications overrides the notification endpoints of the configuration with the ones of the generated
+// notifications Secret, which carry the header values taken from Secrets. The registry parses the override
+// as YAML, of which JSON is a subset, and the values never reach the ConfigMap.
+func (f *PodFactory) addNotifications(registry *registryoperatordevv1alpha1.Registry, template *apiv1.PodTemplateSpec) {
+	if !HasSecretHeaders(registry) {
+		return
+	}
+	template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env,
+		secretEnvVar("REGISTRY_NOTIFICATIONS_ENDPOINTS", NotificationsSecretName(registry), NotificationsSecretKey))
+}
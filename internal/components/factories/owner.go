@@ -0,0 +1,14 @@
+package factories
+
+import (
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ownerReferences makes the registry the controller of a child resource,
+// so that changes to the child are reported to the registry and it is garbage collected with it.
+func ownerReferences(registry *registryoperatordevv1alpha1.Registry) []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		*metav1.NewControllerRef(registry, registryoperatordevv1alpha1.GroupVersion.WithKind("Registry")),
+	}
+}
@@ -0,0 +1,51 @@
+package factories
+
+import (
+	"fmt"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	apiv1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+type PersistentVolumeClaimFactory struct{}
+
+func NewPersistentVolumeClaimFactory() *PersistentVolumeClaimFactory {
+	return &PersistentVolumeClaimFactory{}
+}
+
+// NewPersistentVolumeClaim creates a Kubernetes PersistentVolumeClaim for registries using filesystem storage.
+func (f *PersistentVolumeClaimFactory) NewPersistentVolumeClaim(
+	registry *registryoperatordevv1alpha1.Registry,
+) (*apiv1.PersistentVolumeClaim, error) {
+	filesystem := registry.Spec.Storage.Filesystem
+	if registry.Spec.Storage.Type != registryoperatordevv1alpha1.StorageTypeFilesystem || filesystem == nil {
+		return nil, fmt.Errorf("storage type %s does not use a persistent volume claim", registry.Spec.Storage.Type)
+	}
+
+	accessModes := filesystem.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []apiv1.PersistentVolumeAccessMode{apiv1.ReadWriteOnce}
+	}
+
+	return &apiv1.PersistentVolumeClaim{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            registry.Name,
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+		},
+		Spec: apiv1.PersistentVolumeClaimSpec{
+			AccessModes:      accessModes,
+			StorageClassName: filesystem.StorageClassName,
+			Resources: apiv1.VolumeResourceRequirements{
+				Requests: apiv1.ResourceList{
+					apiv1.ResourceStorage: filesystem.Size,
+				},
+			},
+		},
+	}, nil
+}
@@ -5,45 +5,309 @@ import (
 
 	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
+const (
+	// RegistryImage is the image of the CNCF Distribution registry run by the operator.
+	RegistryImage = "registry:2"
+
+	// registryDataPath is the directory where the registry keeps its data.
+	registryDataPath = "/var/lib/registry"
+	// registryConfigPath is the directory where the registry configuration is mounted.
+	registryConfigPath = "/etc/distribution"
+	// registryPort is the port the registry listens on.
+	registryPort = 5000
+
+	// S3AccessKeySecretKey is the key of the S3 access key in the credentials Secret.
+	S3AccessKeySecretKey = "accessKey"
+	// S3SecretKeySecretKey is the key of the S3 secret key in the credentials Secret.
+	S3SecretKeySecretKey = "secretKey"
+	// GCSKeyFileSecretKey is the key of the GCS service account JSON in the credentials Secret.
+	GCSKeyFileSecretKey = "key.json"
+	// AzureAccountKeySecretKey is the key of the Azure storage account key in the credentials Secret.
+	AzureAccountKeySecretKey = "accountKey"
+
+	// gcsCredentialsPath is the directory where the GCS credentials are mounted.
+	gcsCredentialsPath = "/etc/distribution-gcs"
+	// authPath is the directory where the authentication files are mounted.
+	authPath = "/etc/distribution-auth"
+	// tlsPath is the directory where the serving certificate is mounted.
+	tlsPath = "/etc/distribution-tls"
+)
+
 type PodFactory struct{}
 
 func NewPodFactory() *PodFactory {
 	return &PodFactory{}
 }
 
-// CreatePod creates a Kubernetes Pod based on the registry specification.
-func (f *PodFactory) NewPod(registry *registryoperatordevv1alpha1.Registry) (*apiv1.Pod, error) {
+// NewPodTemplate creates a Kubernetes Pod template based on the registry specification.
+func (f *PodFactory) NewPodTemplate(registry *registryoperatordevv1alpha1.Registry) (*apiv1.PodTemplateSpec, error) {
+	var template *apiv1.PodTemplateSpec
 	switch registry.Spec.Storage.Type {
 	case registryoperatordevv1alpha1.StorageTypeInMemory:
-		return f.createInMemoryPod(registry), nil
+		template = f.createInMemoryPodTemplate(registry)
+	case registryoperatordevv1alpha1.StorageTypeFilesystem:
+		template = f.createFilesystemPodTemplate(registry)
+	case registryoperatordevv1alpha1.StorageTypeS3:
+		template = f.createS3PodTemplate(registry)
+	case registryoperatordevv1alpha1.StorageTypeGCS:
+		template = f.createGCSPodTemplate(registry)
+	case registryoperatordevv1alpha1.StorageTypeAzure:
+		template = f.createAzurePodTemplate(registry)
 	default:
 		return nil, fmt.Errorf("storage type %s not supported", registry.Spec.Storage.Type)
 	}
+
+	f.addHTTPSecret(registry, template)
+	f.addAuth(registry, template)
+	f.addTLS(registry, template)
+	f.addProxy(registry, template)
+	f.addCache(registry, template)
+	f.addNotifications(registry, template)
+	return template, nil
 }
 
-// createInMemoryPod generates a pod configuration for in-memory storage.
-func (f *PodFactory) createInMemoryPod(registry *registryoperatordevv1alpha1.Registry) *apiv1.Pod {
-	return &apiv1.Pod{
-		ObjectMeta: ctrl.ObjectMeta{
-			Name:      registry.Name,
-			Namespace: registry.Namespace,
-			Labels: map[string]string{
-				"app":      "registry",
-				"registry": registry.Name,
+// addCache injects the password of the redis cache.
+func (f *PodFactory) addCache(registry *registryoperatordevv1alpha1.Registry, template *apiv1.PodTemplateSpec) {
+	cache := registry.Spec.Cache
+	if cache == nil || cache.Type != registryoperatordevv1alpha1.CacheTypeRedis {
+		return
+	}
+	name := RedisPasswordSecretName(registry)
+	if name == "" {
+		return
+	}
+	template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env,
+		secretEnvVar("REGISTRY_REDIS_PASSWORD", name, apiv1.BasicAuthPasswordKey),
+	)
+}
+
+// addHTTPSecret injects the HTTP secret shared by the registry pods.
+func (f *PodFactory) addHTTPSecret(registry *registryoperatordevv1alpha1.Registry, template *apiv1.PodTemplateSpec) {
+	template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env,
+		secretEnvVar("REGISTRY_HTTP_SECRET", HTTPSecretName(registry), HTTPSecretKey),
+	)
+}
+
+// addProxy injects the credentials of the remote registry a pull-through cache authenticates with.
+func (f *PodFactory) addProxy(registry *registryoperatordevv1alpha1.Registry, template *apiv1.PodTemplateSpec) {
+	proxy := registry.Spec.Proxy
+	if proxy == nil || proxy.CredentialsSecretRef == nil {
+		return
+	}
+	template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env,
+		secretEnvVar("REGISTRY_PROXY_USERNAME", proxy.CredentialsSecretRef.Name, apiv1.BasicAuthUsernameKey),
+		secretEnvVar("REGISTRY_PROXY_PASSWORD", proxy.CredentialsSecretRef.Name, apiv1.BasicAuthPasswordKey),
+	)
+}
+
+// addTLS mounts the serving certificate and switches the probes to HTTPS.
+func (f *PodFactory) addTLS(registry *registryoperatordevv1alpha1.Registry, template *apiv1.PodTemplateSpec) {
+	if registry.Spec.TLS == nil {
+		return
+	}
+	container := &template.Spec.Containers[0]
+	container.ReadinessProbe.HTTPGet.Scheme = apiv1.URISchemeHTTPS
+	container.LivenessProbe.HTTPGet.Scheme = apiv1.URISchemeHTTPS
+	container.VolumeMounts = append(container.VolumeMounts, apiv1.VolumeMount{
+		Name:      "tls",
+		MountPath: tlsPath,
+		ReadOnly:  true,
+	})
+	template.Spec.Volumes = append(template.Spec.Volumes, apiv1.Volume{
+		Name: "tls",
+		VolumeSource: apiv1.VolumeSource{
+			Secret: &apiv1.SecretVolumeSource{
+				SecretName: TLSSecretName(registry),
+				Items: []apiv1.KeyToPath{
+					{
+						Key:  apiv1.TLSCertKey,
+						Path: apiv1.TLSCertKey,
+					},
+					{
+						Key:  apiv1.TLSPrivateKeyKey,
+						Path: apiv1.TLSPrivateKeyKey,
+					},
+				},
+			},
+		},
+	})
+}
+
+// addAuth mounts the files the registry authenticates clients with.
+func (f *PodFactory) addAuth(registry *registryoperatordevv1alpha1.Registry, template *apiv1.PodTemplateSpec) {
+	auth := registry.Spec.Auth
+	if auth == nil || auth.Htpasswd == nil {
+		return
+	}
+	template.Spec.Containers[0].VolumeMounts = append(template.Spec.Containers[0].VolumeMounts, apiv1.VolumeMount{
+		Name:      "htpasswd",
+		MountPath: authPath,
+		ReadOnly:  true,
+	})
+	template.Spec.Volumes = append(template.Spec.Volumes, apiv1.Volume{
+		Name: "htpasswd",
+		VolumeSource: apiv1.VolumeSource{
+			Secret: &apiv1.SecretVolumeSource{
+				SecretName: HtpasswdSecretName(registry),
+				Items: []apiv1.KeyToPath{
+					{
+						Key:  HtpasswdSecretKey,
+						Path: HtpasswdSecretKey,
+					},
+				},
+			},
+		},
+	})
+}
+
+// createInMemoryPodTemplate generates a pod template for in-memory storage.
+func (f *PodFactory) createInMemoryPodTemplate(registry *registryoperatordevv1alpha1.Registry) *apiv1.PodTemplateSpec {
+	return f.createBasePodTemplate(registry)
+}
+
+// createFilesystemPodTemplate generates a pod template for filesystem storage,
+// mounting the registry PersistentVolumeClaim as the data directory.
+func (f *PodFactory) createFilesystemPodTemplate(registry *registryoperatordevv1alpha1.Registry) *apiv1.PodTemplateSpec {
+	template := f.createBasePodTemplate(registry)
+	template.Spec.Containers[0].VolumeMounts = append(template.Spec.Containers[0].VolumeMounts, apiv1.VolumeMount{
+		Name:      "data",
+		MountPath: registryDataPath,
+	})
+	template.Spec.Volumes = append(template.Spec.Volumes, apiv1.Volume{
+		Name: "data",
+		VolumeSource: apiv1.VolumeSource{
+			PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{
+				ClaimName: registry.Name,
+			},
+		},
+	})
+	return template
+}
+
+// createS3PodTemplate generates a pod template for S3 storage,
+// injecting the credentials from the referenced Secret as environment variables.
+func (f *PodFactory) createS3PodTemplate(registry *registryoperatordevv1alpha1.Registry) *apiv1.PodTemplateSpec {
+	template := f.createBasePodTemplate(registry)
+	s3 := registry.Spec.Storage.S3
+	if s3 == nil || s3.CredentialsSecretRef == nil {
+		return template
+	}
+	template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env,
+		secretEnvVar("REGISTRY_STORAGE_S3_ACCESSKEY", s3.CredentialsSecretRef.Name, S3AccessKeySecretKey),
+		secretEnvVar("REGISTRY_STORAGE_S3_SECRETKEY", s3.CredentialsSecretRef.Name, S3SecretKeySecretKey),
+	)
+	return template
+}
+
+// createGCSPodTemplate generates a pod template for GCS storage,
+// mounting the service account key from the referenced Secret as a file.
+func (f *PodFactory) createGCSPodTemplate(registry *registryoperatordevv1alpha1.Registry) *apiv1.PodTemplateSpec {
+	template := f.createBasePodTemplate(registry)
+	gcs := registry.Spec.Storage.GCS
+	if gcs == nil || gcs.CredentialsSecretRef == nil {
+		return template
+	}
+	template.Spec.Containers[0].VolumeMounts = append(template.Spec.Containers[0].VolumeMounts, apiv1.VolumeMount{
+		Name:      "gcs-credentials",
+		MountPath: gcsCredentialsPath,
+		ReadOnly:  true,
+	})
+	template.Spec.Volumes = append(template.Spec.Volumes, apiv1.Volume{
+		Name: "gcs-credentials",
+		VolumeSource: apiv1.VolumeSource{
+			Secret: &apiv1.SecretVolumeSource{
+				SecretName: gcs.CredentialsSecretRef.Name,
+				Items: []apiv1.KeyToPath{
+					{
+						Key:  GCSKeyFileSecretKey,
+						Path: GCSKeyFileSecretKey,
+					},
+				},
+			},
+		},
+	})
+	return template
+}
+
+// createAzurePodTemplate generates a pod template for Azure storage,
+// injecting the account key from the referenced Secret as an environment variable.
+func (f *PodFactory) createAzurePodTemplate(registry *registryoperatordevv1alpha1.Registry) *apiv1.PodTemplateSpec {
+	template := f.createBasePodTemplate(registry)
+	azure := registry.Spec.Storage.Azure
+	if azure == nil {
+		return template
+	}
+	template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env,
+		secretEnvVar("REGISTRY_STORAGE_AZURE_ACCOUNTKEY", azure.CredentialsSecretRef.Name, AzureAccountKeySecretKey),
+	)
+	return template
+}
+
+// selectorLabels returns the labels identifying the pods of the registry.
+func selectorLabels(registry *registryoperatordevv1alpha1.Registry) map[string]string {
+	return map[string]string{
+		"app":      "registry",
+		"registry": registry.Name,
+	}
+}
+
+// secretEnvVar returns an environment variable sourced from a key of a Secret.
+func secretEnvVar(name, secretName, key string) apiv1.EnvVar {
+	return apiv1.EnvVar{
+		Name: name,
+		ValueFrom: &apiv1.EnvVarSource{
+			SecretKeyRef: &apiv1.SecretKeySelector{
+				LocalObjectReference: apiv1.LocalObjectReference{
+					Name: secretName,
+				},
+				Key: key,
 			},
 		},
+	}
+}
+
+// createBasePodTemplate generates a pod template shared by all storage types.
+func (f *PodFactory) createBasePodTemplate(registry *registryoperatordevv1alpha1.Registry) *apiv1.PodTemplateSpec {
+	return &apiv1.PodTemplateSpec{
+		ObjectMeta: ctrl.ObjectMeta{
+			Labels: selectorLabels(registry),
+		},
 		Spec: apiv1.PodSpec{
 			Containers: []apiv1.Container{
 				{
 					Name:  registry.Name,
-					Image: "registry:2",
+					Image: RegistryImage,
+					Args:  []string{registryConfigPath + "/config.yml"},
+					Ports: []apiv1.ContainerPort{
+						{
+							Name:          "http",
+							ContainerPort: registryPort,
+						},
+					},
+					ReadinessProbe: &apiv1.Probe{
+						ProbeHandler: apiv1.ProbeHandler{
+							HTTPGet: &apiv1.HTTPGetAction{
+								Path: "/",
+								Port: intstr.FromString("http"),
+							},
+						},
+					},
+					LivenessProbe: &apiv1.Probe{
+						ProbeHandler: apiv1.ProbeHandler{
+							HTTPGet: &apiv1.HTTPGetAction{
+								Path: "/",
+								Port: intstr.FromString("http"),
+							},
+						},
+					},
 					VolumeMounts: []apiv1.VolumeMount{
 						{
 							Name:      "config",
-							MountPath: "/etc/distribution",
+							MountPath: registryConfigPath,
 						},
 					},
 				},
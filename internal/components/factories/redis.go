@@ -0,0 +1,185 @@
+package factories
+
+import (
+	"fmt"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// RedisImage is the image of the redis deployed as the cache of a registry.
+	RedisImage = "redis:7-alpine"
+
+	// redisPort is the port redis listens on.
+	redisPort = 6379
+)
+
+type RedisFactory struct{}
+
+func NewRedisFactory() *RedisFactory {
+	return &RedisFactory{}
+}
+
+// ManagedRedis reports whether the operator deploys the redis cache of the registry.
+func ManagedRedis(registry *registryoperatordevv1alpha1.Registry) bool {
+	cache := registry.Spec.Cache
+	return cache != nil && cache.Type == registryoperatordevv1alpha1.CacheTypeRedis && cache.Redis == nil
+}
+
+// RedisName returns the name of the Deployment, Service and password Secret of the redis deployed for the registry.
+func RedisName(registry *registryoperatordevv1alpha1.Registry) string {
+	return registry.Name + "-redis"
+}
+
+// RedisAddr returns the address of the redis cache of the registry.
+func RedisAddr(registry *registryoperatordevv1alpha1.Registry) string {
+	if redis := registry.Spec.Cache.Redis; redis != nil {
+		return redis.Addr
+	}
+	return fmt.Sprintf("%s.%s.svc:%d", RedisName(registry), registry.Namespace, redisPort)
+}
+
+// RedisPasswordSecretName returns the name of the Secret with the password of the redis cache of the registry,
+// or an empty string if redis is accessed without one.
+func RedisPasswordSecretName(registry *registryoperatordevv1alpha1.Registry) string {
+	redis := registry.Spec.Cache.Redis
+	switch {
+	case redis == nil:
+		return RedisName(registry)
+	case redis.PasswordSecretRef != nil:
+		return redis.PasswordSecretRef.Name
+	default:
+		return ""
+	}
+}
+
+// redisLabels returns the labels identifying the redis pods of the registry.
+// They differ from the registry pod labels, so that the registry Service never routes to redis.
+func redisLabels(registry *registryoperatordevv1alpha1.Registry) map[string]string {
+	return map[string]string{
+		"app":      "registry-redis",
+		"registry": registry.Name,
+	}
+}
+
+// NewRedisPasswordSecret creates a Kubernetes Secret with a random password for the redis deployed for the registry.
+func (f *RedisFactory) NewRedisPasswordSecret(registry *registryoperatordevv1alpha1.Registry) (*apiv1.Secret, error) {
+	password, err := NewPassword()
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.Secret{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            RedisName(registry),
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+		},
+		StringData: map[string]string{
+			apiv1.BasicAuthPasswordKey: password,
+		},
+	}, nil
+}
+
+// NewRedisDeployment creates a Kubernetes Deployment running a redis that only serves as a cache:
+// nothing is persisted and the least recently used keys are evicted once it is full.
+func (f *RedisFactory) NewRedisDeployment(registry *registryoperatordevv1alpha1.Registry) (*appsv1.Deployment, error) {
+	replicas := int32(1)
+	probe := &apiv1.Probe{
+		ProbeHandler: apiv1.ProbeHandler{
+			TCPSocket: &apiv1.TCPSocketAction{
+				Port: intstr.FromString("redis"),
+			},
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            RedisName(registry),
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: redisLabels(registry),
+			},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: ctrl.ObjectMeta{
+					Labels: redisLabels(registry),
+				},
+				Spec: apiv1.PodSpec{
+					Containers: []apiv1.Container{
+						{
+							Name:  "redis",
+							Image: RedisImage,
+							Args: []string{
+								"--save", "",
+								"--appendonly", "no",
+								"--maxmemory", "128mb",
+								"--maxmemory-policy", "allkeys-lru",
+								"--requirepass", "$(REDIS_PASSWORD)",
+							},
+							Env: []apiv1.EnvVar{
+								secretEnvVar("REDIS_PASSWORD", RedisName(registry), apiv1.BasicAuthPasswordKey),
+							},
+							Ports: []apiv1.ContainerPort{
+								{
+									Name:          "redis",
+									ContainerPort: redisPort,
+								},
+							},
+							ReadinessProbe: probe,
+							LivenessProbe:  probe.DeepCopy(),
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := setConfigHash(deployment, deployment.Spec); err != nil {
+		return nil, err
+	}
+	return deployment, nil
+}
+
+// NewRedisService creates a Kubernetes Service exposing the redis deployed for the registry.
+func (f *RedisFactory) NewRedisService(registry *registryoperatordevv1alpha1.Registry) (*apiv1.Service, error) {
+	service := &apiv1.Service{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            RedisName(registry),
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+		},
+		Spec: apiv1.ServiceSpec{
+			Type:     apiv1.ServiceTypeClusterIP,
+			Selector: redisLabels(registry),
+			Ports: []apiv1.ServicePort{
+				{
+					Name:       "redis",
+					Port:       redisPort,
+					TargetPort: intstr.FromString("redis"),
+				},
+			},
+		},
+	}
+	if err := setConfigHash(service, service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
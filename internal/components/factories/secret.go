@@ -0,0 +1,236 @@
+package factories
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal"
+	"golang.org/x/crypto/bcrypt"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// HtpasswdSecretKey is the key of the htpasswd file in the generated htpasswd Secret.
+	HtpasswdSecretKey = "htpasswd"
+	// HtpasswdHMACKeySecretKey is the key of the random key the credentials HMAC of the htpasswd Secret is keyed with.
+	// It is not mounted into the registry pods.
+	HtpasswdHMACKeySecretKey = "hmac-key"
+	// AdminUsername is the name of the user created when no credentials Secret is referenced.
+	AdminUsername = "admin"
+	// HTTPSecretKey is the key of the shared HTTP secret in the generated HTTP secret Secret.
+	HTTPSecretKey = "secret"
+)
+
+type SecretFactory struct {
+	ServiceFactory *ServiceFactory
+}
+
+func NewSecretFactory(serviceFactory *ServiceFactory) *SecretFactory {
+	return &SecretFactory{ServiceFactory: serviceFactory}
+}
+
+// HtpasswdSecretName returns the name of the Secret holding the htpasswd file of the registry.
+func HtpasswdSecretName(registry *registryoperatordevv1alpha1.Registry) string {
+	return registry.Name + "-htpasswd"
+}
+
+// AdminCredentialsSecretName returns the name of the Secret holding the generated admin credentials of the registry.
+func AdminCredentialsSecretName(registry *registryoperatordevv1alpha1.Registry) string {
+	return registry.Name + "-admin-credentials"
+}
+
+// HTTPSecretName returns the name of the Secret holding the HTTP secret shared by the registry pods.
+func HTTPSecretName(registry *registryoperatordevv1alpha1.Registry) string {
+	return registry.Name + "-http-secret"
+}
+
+// NewHTTPSecret creates a Kubernetes Secret with a random HTTP secret for the registry pods.
+// The pods sign the state of resumable uploads with it, so that an upload may continue on any replica.
+// The Secret records the value of the rotation annotation of the registry it was generated for.
+func (f *SecretFactory) NewHTTPSecret(registry *registryoperatordevv1alpha1.Registry) (*apiv1.Secret, error) {
+	secret, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.Secret{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            HTTPSecretName(registry),
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+			Annotations: map[string]string{
+				registryoperatordevv1alpha1.RotateHTTPSecretAnnotation: registry.Annotations[registryoperatordevv1alpha1.RotateHTTPSecretAnnotation],
+			},
+		},
+		StringData: map[string]string{
+			HTTPSecretKey: secret,
+		},
+	}, nil
+}
+
+// NewAdminCredentialsSecret creates a Kubernetes Secret with random credentials for the admin user of the registry.
+func (f *SecretFactory) NewAdminCredentialsSecret(registry *registryoperatordevv1alpha1.Registry) (*apiv1.Secret, error) {
+	password, err := NewPassword()
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.Secret{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            AdminCredentialsSecretName(registry),
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+		},
+		Type: apiv1.SecretTypeBasicAuth,
+		StringData: map[string]string{
+			apiv1.BasicAuthUsernameKey: AdminUsername,
+			apiv1.BasicAuthPasswordKey: password,
+		},
+	}, nil
+}
+
+// NewHtpasswdSecret creates a Kubernetes Secret holding an htpasswd file with bcrypt entries for the given
+// username to password mapping. The Secret is annotated with an HMAC of the credentials, as the salted
+// entries differ every time they are generated. The HMAC is keyed with a random key kept in the Secret,
+// so that the annotation cannot be used to guess the passwords.
+func (f *SecretFactory) NewHtpasswdSecret(
+	registry *registryoperatordevv1alpha1.Registry,
+	credentials map[string]string,
+) (*apiv1.Secret, error) {
+	htpasswd, err := htpasswdFile(credentials)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	hash, err := HMAC(key, credentials)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.Secret{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            HtpasswdSecretName(registry),
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+			Annotations: map[string]string{
+				internal.ConfigHashAnnotation: hash,
+			},
+		},
+		Data: map[string][]byte{
+			HtpasswdSecretKey:        htpasswd,
+			HtpasswdHMACKeySecretKey: key,
+		},
+	}, nil
+}
+
+// RegistryUserSecretName returns the name of the Secret holding the credentials of the registry user.
+func RegistryUserSecretName(user *registryoperatordevv1alpha1.RegistryUser) string {
+	return user.Name + "-registry-credentials"
+}
+
+// NewPassword returns a random password.
+func NewPassword() (string, error) {
+	return randomString(32)
+}
+
+// NewRegistryUserSecret creates a kubernetes.io/dockerconfigjson Secret with the credentials of the
+// registry user, valid for every host of the registry. The credentials are also kept in the username
+// and password keys, the htpasswd file of the registry is generated from them.
+func (f *SecretFactory) NewRegistryUserSecret(
+	user *registryoperatordevv1alpha1.RegistryUser,
+	registry *registryoperatordevv1alpha1.Registry,
+	password string,
+) (*apiv1.Secret, error) {
+	username := user.EffectiveUsername()
+	dockerConfig, err := dockerConfigJSON(f.ServiceFactory.Hosts(registry), username, password)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.Secret{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      RegistryUserSecretName(user),
+			Namespace: user.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(user, registryoperatordevv1alpha1.GroupVersion.WithKind("RegistryUser")),
+			},
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+		},
+		Type: apiv1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			apiv1.DockerConfigJsonKey:  dockerConfig,
+			apiv1.BasicAuthUsernameKey: []byte(username),
+			apiv1.BasicAuthPasswordKey: []byte(password),
+		},
+	}, nil
+}
+
+// dockerConfigJSON renders a docker config file with the credentials for each of the hosts.
+func dockerConfigJSON(hosts []string, username, password string) ([]byte, error) {
+	type authEntry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	auths := make(map[string]authEntry, len(hosts))
+	for _, host := range hosts {
+		auths[host] = authEntry{
+			Username: username,
+			Password: password,
+			Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+		}
+	}
+	return json.Marshal(map[string]any{"auths": auths})
+}
+
+// htpasswdFile renders bcrypt htpasswd entries sorted by username.
+func htpasswdFile(credentials map[string]string) ([]byte, error) {
+	usernames := make([]string, 0, len(credentials))
+	for username := range credentials {
+		usernames = append(usernames, username)
+	}
+	slices.Sort(usernames)
+
+	var b strings.Builder
+	for _, username := range usernames {
+		if username == "" || strings.ContainsAny(username, ":\n") {
+			return nil, fmt.Errorf("invalid registry username %q", username)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(credentials[username]), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash the password of user %s: %w", username, err)
+		}
+		fmt.Fprintf(&b, "%s:%s\n", username, hash)
+	}
+	return []byte(b.String()), nil
+}
+
+// randomString returns a URL safe random string encoding the given number of random bytes.
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
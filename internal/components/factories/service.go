@@ -0,0 +1,83 @@
+package factories
+
+import (
+	"fmt"
+	"maps"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+type ServiceFactory struct{}
+
+func NewServiceFactory() *ServiceFactory {
+	return &ServiceFactory{}
+}
+
+// NewService creates a Kubernetes Service exposing the registry pods.
+func (f *ServiceFactory) NewService(registry *registryoperatordevv1alpha1.Registry) (*apiv1.Service, error) {
+	serviceType := registry.Spec.Service.Type
+	if serviceType == "" {
+		serviceType = apiv1.ServiceTypeClusterIP
+	}
+
+	service := &apiv1.Service{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:            registry.Name,
+			Namespace:       registry.Namespace,
+			OwnerReferences: ownerReferences(registry),
+			Labels: map[string]string{
+				"app":      "registry",
+				"registry": registry.Name,
+			},
+			Annotations: maps.Clone(registry.Spec.Service.Annotations),
+		},
+		Spec: apiv1.ServiceSpec{
+			Type:     serviceType,
+			Selector: selectorLabels(registry),
+			Ports: []apiv1.ServicePort{
+				{
+					Name:       "http",
+					Port:       f.port(registry),
+					TargetPort: intstr.FromString("http"),
+				},
+			},
+		},
+	}
+	if err := setConfigHash(service, service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// URL returns the in-cluster URL under which the registry Service is reachable.
+func (f *ServiceFactory) URL(registry *registryoperatordevv1alpha1.Registry) string {
+	if registry.Spec.TLS != nil {
+		return "https://" + f.Host(registry)
+	}
+	return "http://" + f.Host(registry)
+}
+
+// Host returns the in-cluster host and port under which the registry Service is reachable.
+func (f *ServiceFactory) Host(registry *registryoperatordevv1alpha1.Registry) string {
+	return fmt.Sprintf("%s.%s.svc:%d", registry.Name, registry.Namespace, f.port(registry))
+}
+
+// Hosts returns the hosts clients may address the registry with, the in-cluster one first.
+func (f *ServiceFactory) Hosts(registry *registryoperatordevv1alpha1.Registry) []string {
+	hosts := []string{f.Host(registry)}
+	if registry.Spec.Exposure != nil {
+		hosts = append(hosts, registry.Spec.Exposure.Host)
+	}
+	return hosts
+}
+
+// port returns the Service port of the registry.
+func (f *ServiceFactory) port(registry *registryoperatordevv1alpha1.Registry) int32 {
+	if registry.Spec.Service.Port == 0 {
+		return registryPort
+	}
+	return registry.Spec.Service.Port
+}
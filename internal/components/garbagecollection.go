@@ -0,0 +1,71 @@
+package components
+
+import (
+	"context"
+	"slices"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// jobFinished reports whether the Job completed or failed.
+func jobFinished(job *batchv1.Job) bool {
+	return slices.ContainsFunc(job.Status.Conditions, func(condition batchv1.JobCondition) bool {
+		return (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) &&
+			condition.Status == apiv1.ConditionTrue
+	})
+}
+
+// RefreshGarbageCollection records the garbage collection Job the registry has to be held read-only for
+// in its status, or clears it when none is pending or running. Jobs of a CronJob that was removed from the spec
+// are still waited for, they may be running against the storage.
+func (ro *RegistryOperations) RefreshGarbageCollection(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	jobs := &batchv1.JobList{}
+	err := ro.Client.List(ctx, jobs, client.InNamespace(registry.Namespace),
+		client.MatchingLabels(factories.GarbageCollectionLabels(registry)))
+	if err != nil {
+		return err
+	}
+
+	// ConcurrencyPolicy forbids overlapping Jobs, the oldest one is the one to finish first.
+	slices.SortFunc(jobs.Items, func(a, b batchv1.Job) int {
+		return a.CreationTimestamp.Compare(b.CreationTimestamp.Time)
+	})
+	registry.Status.GarbageCollectionJob = ""
+	for _, job := range jobs.Items {
+		if !jobFinished(&job) {
+			registry.Status.GarbageCollectionJob = job.Name
+			break
+		}
+	}
+	return nil
+}
+
+// ResumeGarbageCollection starts the garbage collection Job the registry is held read-only for.
+// It must only be called once every registry pod serves the read-only configuration.
+func (ro *RegistryOperations) ResumeGarbageCollection(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	name := registry.Status.GarbageCollectionJob
+	if name == "" {
+		return nil
+	}
+
+	job := &batchv1.Job{}
+	err := ro.Client.Get(ctx, client.ObjectKey{Namespace: registry.Namespace, Name: name}, job)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if job.Spec.Suspend == nil || !*job.Spec.Suspend {
+		return nil
+	}
+
+	l.Info("Starting garbage collection for", "registry", registry.Name, "job", name)
+	suspend := false
+	job.Spec.Suspend = &suspend
+	return ro.Client.Update(ctx, job)
+}
@@ -0,0 +1,50 @@
+package components
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+)
+
+// checkHTTPSecretDrift reports whether the HTTP secret of the registry is missing
+// or was generated before the last requested rotation.
+func (ro *RegistryOperations) checkHTTPSecretDrift(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	desired, err := ro.SecretFactory.NewHTTPSecret(registry)
+	if err != nil {
+		return false, err
+	}
+	existing, exists, err := ro.getChild(ctx, desired)
+	if err != nil || !exists {
+		return !exists, err
+	}
+	rotation := registryoperatordevv1alpha1.RotateHTTPSecretAnnotation
+	return existing.GetAnnotations()[rotation] != registry.Annotations[rotation], nil
+}
+
+// applyHTTPSecret generates the HTTP secret of the registry, and generates it again when a rotation is requested.
+// The secrets hash of the pod template changes with it, so the pods are rolled onto the new secret.
+func (ro *RegistryOperations) applyHTTPSecret(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	outdated, err := ro.checkHTTPSecretDrift(ctx, registry)
+	if err != nil || !outdated {
+		return err
+	}
+
+	desired, err := ro.SecretFactory.NewHTTPSecret(registry)
+	if err != nil {
+		return err
+	}
+	existing, exists, err := ro.getChild(ctx, desired)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		l.Info("Creating HTTP secret Secret for", "registry", registry.Name)
+		return ro.Client.Create(ctx, desired)
+	}
+	l.Info("Rotating HTTP secret Secret for", "registry", registry.Name)
+	desired.SetResourceVersion(existing.GetResourceVersion())
+	return ro.Client.Update(ctx, desired)
+}
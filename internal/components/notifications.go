@@ -0,0 +1,81 @@
+package components
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// notificationHeaderValue returns a resolver of the notification header values the registry takes from Secrets.
+func (ro *RegistryOperations) notificationHeaderValue(
+	ctx context.Context,
+	registry *registryoperatordevv1alpha1.Registry,
+) factories.SecretValue {
+	return func(selector *apiv1.SecretKeySelector) (string, bool, error) {
+		optional := selector.Optional != nil && *selector.Optional
+		secret := &apiv1.Secret{}
+		err := ro.Client.Get(ctx, client.ObjectKey{Namespace: registry.Namespace, Name: selector.Name}, secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) && optional {
+				return "", false, nil
+			}
+			return "", false, fmt.Errorf("failed to get the notification header Secret %s: %w", selector.Name, err)
+		}
+		value, ok := secret.Data[selector.Key]
+		if !ok && !optional {
+			return "", false, fmt.Errorf("notification header Secret %s has no key %s", selector.Name, selector.Key)
+		}
+		return string(value), ok, nil
+	}
+}
+
+// checkNotificationsDrift reports whether the notifications Secret of the registry is missing
+// or holds other header values than the Secrets they are taken from.
+func (ro *RegistryOperations) checkNotificationsDrift(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	if !factories.HasSecretHeaders(registry) {
+		return false, nil
+	}
+	desired, err := ro.SecretFactory.NewNotificationsSecret(registry, ro.notificationHeaderValue(ctx, registry))
+	if err != nil {
+		return false, err
+	}
+	existing, exists, err := ro.getChild(ctx, desired)
+	if err != nil || !exists {
+		return !exists, err
+	}
+	return !upToDate(existing, desired), nil
+}
+
+// applyNotificationsSecret renders the notification endpoints with the header values taken from Secrets.
+// The secrets hash of the pod template changes with it, so the pods are rolled onto the new values.
+func (ro *RegistryOperations) applyNotificationsSecret(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	if !factories.HasSecretHeaders(registry) {
+		return nil
+	}
+	desired, err := ro.SecretFactory.NewNotificationsSecret(registry, ro.notificationHeaderValue(ctx, registry))
+	if err != nil {
+		return err
+	}
+	existing, exists, err := ro.getChild(ctx, desired)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		l.Info("Creating notifications Secret for", "registry", registry.Name)
+		return ro.Client.Create(ctx, desired)
+	}
+	if upToDate(existing, desired) {
+		return nil
+	}
+	l.Info("Updating notifications Secret for", "registry", registry.Name)
+	desired.SetResourceVersion(existing.GetResourceVersion())
+	return ro.Client.Update(ctx, desired)
+}
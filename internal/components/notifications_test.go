@@ -0,0 +1,120 @@
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+	"github.com/registry-operator/registry-operator/internal/distribution"
+)
+
+func TestApplyRegistryChangesNotificationSecretHeaders(t *testing.T) {
+	ctx := context.Background()
+	// Characters that would break or extend the configuration if the value was expanded into it.
+	const token = `Bearer "quoted" \back\slash $(HOME) ` + "\n" + `url: https://attacker.example.com`
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "audit-token", Namespace: "default"},
+		Data:       map[string][]byte{"authorization": []byte(token)},
+	}
+	registry := &registryoperatordevv1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default", UID: "uid"},
+		Spec: registryoperatordevv1alpha1.RegistrySpec{
+			Storage: registryoperatordevv1alpha1.Storage{Type: registryoperatordevv1alpha1.StorageTypeInMemory},
+			Notifications: &registryoperatordevv1alpha1.RegistryNotifications{
+				Endpoints: []registryoperatordevv1alpha1.NotificationEndpoint{
+					{
+						Name: "audit",
+						URL:  "https://audit.example.com/events",
+						Headers: []registryoperatordevv1alpha1.NotificationHeader{
+							{Name: "X-Source", Value: "registry"},
+							{
+								Name: "Authorization",
+								SecretKeyRef: &apiv1.SecretKeySelector{
+									LocalObjectReference: apiv1.LocalObjectReference{Name: secret.Name},
+									Key:                  "authorization",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	ro := newTestRegistryOperations(t, registry, secret)
+	if err := ro.ApplyRegistryChanges(ctx, registry); err != nil {
+		t.Fatalf("ApplyRegistryChanges() error = %v", err)
+	}
+
+	notifications := &apiv1.Secret{}
+	key := client.ObjectKey{Namespace: registry.Namespace, Name: factories.NotificationsSecretName(registry)}
+	if err := ro.Client.Get(ctx, key, notifications); err != nil {
+		t.Fatal(err)
+	}
+	var endpoints []distribution.Endpoint
+	if err := json.Unmarshal(notifications.Data[factories.NotificationsSecretKey], &endpoints); err != nil {
+		t.Fatalf("notifications Secret does not hold JSON: %v", err)
+	}
+	if len(endpoints) != 1 || endpoints[0].URL != "https://audit.example.com/events" {
+		t.Fatalf("endpoints = %+v, want the audit endpoint", endpoints)
+	}
+	headers := endpoints[0].Headers
+	if got := headers["Authorization"]; len(got) != 1 || got[0] != token {
+		t.Errorf("Authorization header = %q, want %q", got, token)
+	}
+	if got := headers["X-Source"]; len(got) != 1 || got[0] != "registry" {
+		t.Errorf("X-Source header = %q, want registry", got)
+	}
+
+	configMap := &apiv1.ConfigMap{}
+	if err := ro.Client.Get(ctx, client.ObjectKeyFromObject(registry), configMap); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(configMap.Data["config.yml"], "attacker") {
+		t.Error("ConfigMap contains the value of the header Secret")
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := ro.Client.Get(ctx, client.ObjectKeyFromObject(registry), deployment); err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, env := range deployment.Spec.Template.Spec.Containers[0].Env {
+		if env.Name != "REGISTRY_NOTIFICATIONS_ENDPOINTS" {
+			continue
+		}
+		found = true
+		if env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil || env.ValueFrom.SecretKeyRef.Name != key.Name {
+			t.Errorf("REGISTRY_NOTIFICATIONS_ENDPOINTS is not taken from the Secret %s", key.Name)
+		}
+	}
+	if !found {
+		t.Error("REGISTRY_NOTIFICATIONS_ENDPOINTS is missing")
+	}
+
+	drift, err := ro.CheckRegistryDrift(ctx, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drift {
+		t.Error("expected no drift right after the changes were applied")
+	}
+	secret.Data["authorization"] = []byte("Bearer rotated")
+	if err := ro.Client.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	drift, err = ro.checkNotificationsDrift(ctx, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drift {
+		t.Error("expected drift once the header Secret changed")
+	}
+}
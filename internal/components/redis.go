@@ -0,0 +1,42 @@
+package components
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// checkRedisDrift reports whether the password Secret of the redis deployed for the registry is missing.
+func (ro *RegistryOperations) checkRedisDrift(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	if !factories.ManagedRedis(registry) {
+		return false, nil
+	}
+	desired, err := ro.RedisFactory.NewRedisPasswordSecret(registry)
+	if err != nil {
+		return false, err
+	}
+	_, exists, err := ro.getChild(ctx, desired)
+	return !exists, err
+}
+
+// applyRedisPasswordSecret creates the random password of the redis deployed for the registry.
+// The password is generated once, an existing Secret is kept as is.
+func (ro *RegistryOperations) applyRedisPasswordSecret(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	if !factories.ManagedRedis(registry) {
+		return nil
+	}
+	desired, err := ro.RedisFactory.NewRedisPasswordSecret(registry)
+	if err != nil {
+		return err
+	}
+	_, exists, err := ro.getChild(ctx, desired)
+	if err != nil || exists {
+		return err
+	}
+	l.Info("Creating redis password Secret for", "registry", registry.Name)
+	return ro.Client.Create(ctx, desired)
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"slices"
 
+	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
 	"github.com/registry-operator/registry-operator/internal"
@@ -15,25 +19,48 @@ import (
 )
 
 type RegistryOperations struct {
-	Client           client.Client
-	PodFactory       *factories.PodFactory
-	ConfigMapFactory *factories.ConfigMapFactory
+	Client                       client.Client
+	PodFactory                   *factories.PodFactory
+	DeploymentFactory            *factories.DeploymentFactory
+	ConfigMapFactory             *factories.ConfigMapFactory
+	PersistentVolumeClaimFactory *factories.PersistentVolumeClaimFactory
+	ServiceFactory               *factories.ServiceFactory
+	IngressFactory               *factories.IngressFactory
+	HTTPRouteFactory             *factories.HTTPRouteFactory
+	SecretFactory                *factories.SecretFactory
+	CronJobFactory               *factories.CronJobFactory
+	RedisFactory                 *factories.RedisFactory
 }
 
-func NewRegistryOperations(client client.Client) *RegistryOperations {
-	return &RegistryOperations{Client: client}
+func NewRegistryOperations(client client.Client, tokenAuth *factories.TokenAuthOptions) *RegistryOperations {
+	podFactory := factories.NewPodFactory()
+	configMapFactory := factories.NewConfigMapFactory(tokenAuth)
+	serviceFactory := factories.NewServiceFactory()
+	return &RegistryOperations{
+		Client:                       client,
+		PodFactory:                   podFactory,
+		DeploymentFactory:            factories.NewDeploymentFactory(podFactory, configMapFactory),
+		ConfigMapFactory:             configMapFactory,
+		PersistentVolumeClaimFactory: factories.NewPersistentVolumeClaimFactory(),
+		ServiceFactory:               serviceFactory,
+		IngressFactory:               factories.NewIngressFactory(),
+		HTTPRouteFactory:             factories.NewHTTPRouteFactory(serviceFactory),
+		SecretFactory:                factories.NewSecretFactory(serviceFactory),
+		CronJobFactory:               factories.NewCronJobFactory(podFactory),
+		RedisFactory:                 factories.NewRedisFactory(),
+	}
 }
 
-func (ro *RegistryOperations) CheckRegistryPodExists(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+func (ro *RegistryOperations) CheckRegistryDeploymentExists(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
 	l := log.FromContext(ctx)
-	pod := &apiv1.Pod{
+	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      registry.Name,
 			Namespace: registry.Namespace,
 		},
 	}
-	l.Info("Checking if pod exists for", "registry", registry.Name)
-	err := ro.Client.Get(ctx, client.ObjectKeyFromObject(pod), pod)
+	l.Info("Checking if Deployment exists for", "registry", registry.Name)
+	err := ro.Client.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)
 	if err != nil {
 		if client.IgnoreNotFound(err) != nil {
 			return false, err
@@ -43,27 +70,23 @@ func (ro *RegistryOperations) CheckRegistryPodExists(ctx context.Context, regist
 	return true, nil
 }
 
-func (ro *RegistryOperations) GetRegistryPod(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (*apiv1.Pod, error) {
+func (ro *RegistryOperations) GetRegistryDeployment(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (*appsv1.Deployment, error) {
 	l := log.FromContext(ctx)
-	pod := &apiv1.Pod{
+	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      registry.Name,
 			Namespace: registry.Namespace,
 		},
 	}
-	l.Info("Getting pod for", "registry", registry.Name)
-	err := ro.Client.Get(ctx, client.ObjectKeyFromObject(pod), pod)
-	return pod, err
+	l.Info("Getting Deployment for", "registry", registry.Name)
+	err := ro.Client.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)
+	return deployment, err
 }
 
-func (ro *RegistryOperations) CreateRegistryPod(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+func (ro *RegistryOperations) UpdateRegistryDeployment(ctx context.Context, registry *registryoperatordevv1alpha1.Registry, deployment *appsv1.Deployment) error {
 	l := log.FromContext(ctx)
-	pod, err := ro.PodFactory.NewPod(registry)
-	if err != nil {
-		return err
-	}
-	l.Info("Creating pod for", "registry", registry.Name)
-	return ro.Client.Create(ctx, pod)
+	l.Info("Updating Deployment for", "registry", registry.Name)
+	return ro.Client.Update(ctx, deployment)
 }
 
 func (ro *RegistryOperations) UpdateRegistryStatus(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
@@ -72,20 +95,23 @@ func (ro *RegistryOperations) UpdateRegistryStatus(ctx context.Context, registry
 	return ro.Client.Status().Update(ctx, registry)
 }
 
-func (ro *RegistryOperations) DeleteRegistryPod(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+func (ro *RegistryOperations) DeleteRegistryDeployment(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
 	l := log.FromContext(ctx)
-	pod := &apiv1.Pod{
+	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      registry.Name,
 			Namespace: registry.Namespace,
 		},
 	}
-	l.Info("Deleting pod for", "registry", registry.Name)
-	return ro.Client.Delete(ctx, pod)
+	l.Info("Deleting Deployment for", "registry", registry.Name)
+	return ro.Client.Delete(ctx, deployment)
 }
 
 func (ro *RegistryOperations) AddFinalizer(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
 	l := log.FromContext(ctx)
+	if slices.Contains(registry.GetFinalizers(), internal.RegistryFinalizer) {
+		return nil
+	}
 	l.Info("Adding finalizer to", "registry", registry.Name)
 	registry.SetFinalizers(append(registry.GetFinalizers(), internal.RegistryFinalizer))
 	return ro.Client.Update(ctx, registry)
@@ -133,11 +159,23 @@ func (ro *RegistryOperations) CheckRegistryConfigMapExists(ctx context.Context,
 	return true, nil
 }
 
-func (ro *RegistryOperations) CreateRegistryConfigMap(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
-	l := log.FromContext(ctx)
-	l.Info("Creating ConfigMap for", "registry", registry.Name)
-	configMap := ro.ConfigMapFactory.NewConfigMap(registry)
-	return ro.Client.Create(ctx, configMap)
+// ValidateRegistryConfig renders the configuration and the workload of the registry
+// without creating them, reporting specs the operator cannot handle.
+func (ro *RegistryOperations) ValidateRegistryConfig(registry *registryoperatordevv1alpha1.Registry) error {
+	if _, err := ro.ConfigMapFactory.NewConfigMap(registry); err != nil {
+		return err
+	}
+	_, err := ro.DeploymentFactory.NewDeployment(registry)
+	return err
+}
+
+// ConfigHash returns the hash of the rendered registry configuration.
+func (ro *RegistryOperations) ConfigHash(registry *registryoperatordevv1alpha1.Registry) (string, error) {
+	configMap, err := ro.ConfigMapFactory.NewConfigMap(registry)
+	if err != nil {
+		return "", err
+	}
+	return factories.Hash(configMap.Data)
 }
 
 func (ro *RegistryOperations) DeleteRegistryConfigMap(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
@@ -151,3 +189,197 @@ func (ro *RegistryOperations) DeleteRegistryConfigMap(ctx context.Context, regis
 	l.Info("Deleting ConfigMap for", "registry", registry.Name)
 	return ro.Client.Delete(ctx, configMap)
 }
+
+func (ro *RegistryOperations) CheckRegistryPersistentVolumeClaimExists(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	l := log.FromContext(ctx)
+	pvc := &apiv1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      registry.Name,
+			Namespace: registry.Namespace,
+		},
+	}
+	l.Info("Checking if PersistentVolumeClaim exists for", "registry", registry.Name)
+	err := ro.Client.Get(ctx, client.ObjectKeyFromObject(pvc), pvc)
+	if err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func (ro *RegistryOperations) GetRegistryPersistentVolumeClaim(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (*apiv1.PersistentVolumeClaim, error) {
+	l := log.FromContext(ctx)
+	pvc := &apiv1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      registry.Name,
+			Namespace: registry.Namespace,
+		},
+	}
+	l.Info("Getting PersistentVolumeClaim for", "registry", registry.Name)
+	err := ro.Client.Get(ctx, client.ObjectKeyFromObject(pvc), pvc)
+	return pvc, err
+}
+
+func (ro *RegistryOperations) CreateRegistryPersistentVolumeClaim(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	pvc, err := ro.PersistentVolumeClaimFactory.NewPersistentVolumeClaim(registry)
+	if err != nil {
+		return err
+	}
+	l.Info("Creating PersistentVolumeClaim for", "registry", registry.Name)
+	return ro.Client.Create(ctx, pvc)
+}
+
+func (ro *RegistryOperations) DeleteRegistryPersistentVolumeClaim(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	pvc := &apiv1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      registry.Name,
+			Namespace: registry.Namespace,
+		},
+	}
+	l.Info("Deleting PersistentVolumeClaim for", "registry", registry.Name)
+	return ro.Client.Delete(ctx, pvc)
+}
+
+func (ro *RegistryOperations) CheckRegistryServiceExists(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	l := log.FromContext(ctx)
+	service := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      registry.Name,
+			Namespace: registry.Namespace,
+		},
+	}
+	l.Info("Checking if Service exists for", "registry", registry.Name)
+	err := ro.Client.Get(ctx, client.ObjectKeyFromObject(service), service)
+	if err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func (ro *RegistryOperations) DeleteRegistryService(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	service := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      registry.Name,
+			Namespace: registry.Namespace,
+		},
+	}
+	l.Info("Deleting Service for", "registry", registry.Name)
+	return ro.Client.Delete(ctx, service)
+}
+
+// RegistryURL returns the in-cluster URL of the registry Service.
+func (ro *RegistryOperations) RegistryURL(registry *registryoperatordevv1alpha1.Registry) string {
+	return ro.ServiceFactory.URL(registry)
+}
+
+// ReferencedSecrets returns the names of the Secrets in the registry namespace its spec references.
+func ReferencedSecrets(registry *registryoperatordevv1alpha1.Registry) []string {
+	var names []string
+	storage := registry.Spec.Storage
+	if storage.S3 != nil && storage.S3.CredentialsSecretRef != nil {
+		names = append(names, storage.S3.CredentialsSecretRef.Name)
+	}
+	if storage.GCS != nil && storage.GCS.CredentialsSecretRef != nil {
+		names = append(names, storage.GCS.CredentialsSecretRef.Name)
+	}
+	if storage.Azure != nil {
+		names = append(names, storage.Azure.CredentialsSecretRef.Name)
+	}
+	if htpasswdEnabled(registry) && registry.Spec.Auth.Htpasswd.CredentialsSecretRef != nil {
+		names = append(names, registry.Spec.Auth.Htpasswd.CredentialsSecretRef.Name)
+	}
+	if registry.Spec.TLS != nil && registry.Spec.TLS.SecretName != "" {
+		names = append(names, registry.Spec.TLS.SecretName)
+	}
+	if registry.Spec.Proxy != nil && registry.Spec.Proxy.CredentialsSecretRef != nil {
+		names = append(names, registry.Spec.Proxy.CredentialsSecretRef.Name)
+	}
+	if registry.Spec.Distribution != nil && registry.Spec.Distribution.CredentialsSecretRef != nil {
+		names = append(names, registry.Spec.Distribution.CredentialsSecretRef.Name)
+	}
+	if cache := registry.Spec.Cache; cache != nil && cache.Redis != nil && cache.Redis.PasswordSecretRef != nil {
+		names = append(names, cache.Redis.PasswordSecretRef.Name)
+	}
+	if notifications := registry.Spec.Notifications; notifications != nil {
+		for _, endpoint := range notifications.Endpoints {
+			for _, header := range endpoint.Headers {
+				if header.SecretKeyRef != nil {
+					names = append(names, header.SecretKeyRef.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+func (ro *RegistryOperations) CheckRegistryIngressExists(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	l := log.FromContext(ctx)
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      registry.Name,
+			Namespace: registry.Namespace,
+		},
+	}
+	l.Info("Checking if Ingress exists for", "registry", registry.Name)
+	err := ro.Client.Get(ctx, client.ObjectKeyFromObject(ingress), ingress)
+	if err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func (ro *RegistryOperations) DeleteRegistryIngress(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      registry.Name,
+			Namespace: registry.Namespace,
+		},
+	}
+	l.Info("Deleting Ingress for", "registry", registry.Name)
+	return ro.Client.Delete(ctx, ingress)
+}
+
+// CheckRegistryHTTPRouteExists reports whether the HTTPRoute of the registry exists.
+// When the Gateway API is not installed in the cluster, the HTTPRoute is reported as missing.
+func (ro *RegistryOperations) CheckRegistryHTTPRouteExists(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	l := log.FromContext(ctx)
+	route := &gatewayv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      registry.Name,
+			Namespace: registry.Namespace,
+		},
+	}
+	l.Info("Checking if HTTPRoute exists for", "registry", registry.Name)
+	err := ro.Client.Get(ctx, client.ObjectKeyFromObject(route), route)
+	if err != nil {
+		if client.IgnoreNotFound(err) != nil && !meta.IsNoMatchError(err) {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func (ro *RegistryOperations) DeleteRegistryHTTPRoute(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	route := &gatewayv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      registry.Name,
+			Namespace: registry.Namespace,
+		},
+	}
+	l.Info("Deleting HTTPRoute for", "registry", registry.Name)
+	return ro.Client.Delete(ctx, route)
+}
@@ -0,0 +1,75 @@
+package components
+
+import (
+	"context"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+type RegistryUserOperations struct {
+	Client        client.Client
+	SecretFactory *factories.SecretFactory
+}
+
+func NewRegistryUserOperations(client client.Client) *RegistryUserOperations {
+	return &RegistryUserOperations{
+		Client:        client,
+		SecretFactory: factories.NewSecretFactory(factories.NewServiceFactory()),
+	}
+}
+
+func (uo *RegistryUserOperations) GetRegistry(
+	ctx context.Context,
+	user *registryoperatordevv1alpha1.RegistryUser,
+) (*registryoperatordevv1alpha1.Registry, error) {
+	l := log.FromContext(ctx)
+	registry := &registryoperatordevv1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      user.Spec.RegistryRef.Name,
+			Namespace: user.Namespace,
+		},
+	}
+	l.Info("Getting Registry for", "user", user.Name)
+	err := uo.Client.Get(ctx, client.ObjectKeyFromObject(registry), registry)
+	return registry, err
+}
+
+func (uo *RegistryUserOperations) GetRegistryUserSecret(
+	ctx context.Context,
+	user *registryoperatordevv1alpha1.RegistryUser,
+) (*apiv1.Secret, error) {
+	l := log.FromContext(ctx)
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      factories.RegistryUserSecretName(user),
+			Namespace: user.Namespace,
+		},
+	}
+	l.Info("Getting Secret for", "user", user.Name)
+	err := uo.Client.Get(ctx, client.ObjectKeyFromObject(secret), secret)
+	return secret, err
+}
+
+func (uo *RegistryUserOperations) CreateRegistryUserSecret(ctx context.Context, user *registryoperatordevv1alpha1.RegistryUser, secret *apiv1.Secret) error {
+	l := log.FromContext(ctx)
+	l.Info("Creating Secret for", "user", user.Name)
+	return uo.Client.Create(ctx, secret)
+}
+
+func (uo *RegistryUserOperations) UpdateRegistryUserSecret(ctx context.Context, user *registryoperatordevv1alpha1.RegistryUser, secret *apiv1.Secret) error {
+	l := log.FromContext(ctx)
+	l.Info("Updating Secret for", "user", user.Name)
+	return uo.Client.Update(ctx, secret)
+}
+
+func (uo *RegistryUserOperations) UpdateRegistryUserStatus(ctx context.Context, user *registryoperatordevv1alpha1.RegistryUser) error {
+	l := log.FromContext(ctx)
+	l.Info("Updating status for", "user", user.Name)
+	return uo.Client.Status().Update(ctx, user)
+}
@@ -0,0 +1,185 @@
+package components
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// newTestRegistryOperations returns RegistryOperations backed by a fake client holding the given objects.
+func newTestRegistryOperations(t *testing.T, objs ...client.Object) *RegistryOperations {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		gatewayv1.AddToScheme,
+		registryoperatordevv1alpha1.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return NewRegistryOperations(c, nil)
+}
+
+func TestApplyRegistryChangesStorageCredentials(t *testing.T) {
+	const namespace = "default"
+	credentials := func(data map[string]string) *apiv1.Secret {
+		secret := &apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "storage-credentials", Namespace: namespace},
+			Data:       map[string][]byte{},
+		}
+		for key, value := range data {
+			secret.Data[key] = []byte(value)
+		}
+		return secret
+	}
+	ref := &apiv1.LocalObjectReference{Name: "storage-credentials"}
+
+	tests := []struct {
+		name    string
+		storage registryoperatordevv1alpha1.Storage
+		secret  *apiv1.Secret
+		env     map[string]string
+		mount   string
+	}{
+		{
+			name: "s3",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeS3,
+				S3: &registryoperatordevv1alpha1.S3Storage{
+					Bucket:               "registry",
+					Region:               "us-east-1",
+					CredentialsSecretRef: ref,
+				},
+			},
+			secret: credentials(map[string]string{
+				factories.S3AccessKeySecretKey: "s3-access-key-value",
+				factories.S3SecretKeySecretKey: "s3-secret-key-value",
+			}),
+			env: map[string]string{
+				"REGISTRY_STORAGE_S3_ACCESSKEY": factories.S3AccessKeySecretKey,
+				"REGISTRY_STORAGE_S3_SECRETKEY": factories.S3SecretKeySecretKey,
+			},
+		},
+		{
+			name: "gcs",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeGCS,
+				GCS: &registryoperatordevv1alpha1.GCSStorage{
+					Bucket:               "registry",
+					CredentialsSecretRef: ref,
+				},
+			},
+			secret: credentials(map[string]string{
+				factories.GCSKeyFileSecretKey: `{"private_key": "gcs-private-key-value"}`,
+			}),
+			mount: "/etc/distribution-gcs",
+		},
+		{
+			name: "azure",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeAzure,
+				Azure: &registryoperatordevv1alpha1.AzureStorage{
+					AccountName:          "registry",
+					Container:            "registry",
+					Realm:                "core.windows.net",
+					CredentialsSecretRef: *ref,
+				},
+			},
+			secret: credentials(map[string]string{
+				factories.AzureAccountKeySecretKey: "azure-account-key-value",
+			}),
+			env: map[string]string{
+				"REGISTRY_STORAGE_AZURE_ACCOUNTKEY": factories.AzureAccountKeySecretKey,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			registry := &registryoperatordevv1alpha1.Registry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: namespace, UID: "uid"},
+				Spec:       registryoperatordevv1alpha1.RegistrySpec{Storage: tt.storage},
+			}
+			ro := newTestRegistryOperations(t, registry, tt.secret)
+			if err := ro.ApplyRegistryChanges(ctx, registry); err != nil {
+				t.Fatalf("ApplyRegistryChanges() error = %v", err)
+			}
+
+			configMap := &apiv1.ConfigMap{}
+			if err := ro.Client.Get(ctx, client.ObjectKeyFromObject(registry), configMap); err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range tt.secret.Data {
+				for name, data := range configMap.Data {
+					if strings.Contains(data, string(value)) {
+						t.Errorf("ConfigMap key %s contains the value of the Secret key %s", name, key)
+					}
+				}
+			}
+
+			deployment := &appsv1.Deployment{}
+			if err := ro.Client.Get(ctx, client.ObjectKeyFromObject(registry), deployment); err != nil {
+				t.Fatal(err)
+			}
+			container := deployment.Spec.Template.Spec.Containers[0]
+			for name, key := range tt.env {
+				var found bool
+				for _, env := range container.Env {
+					if env.Name != name {
+						continue
+					}
+					found = true
+					if env.Value != "" || env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil {
+						t.Errorf("env %s is not taken from a Secret", name)
+						continue
+					}
+					if selector := env.ValueFrom.SecretKeyRef; selector.Name != ref.Name || selector.Key != key {
+						t.Errorf("env %s = %s/%s, want %s/%s", name, selector.Name, selector.Key, ref.Name, key)
+					}
+				}
+				if !found {
+					t.Errorf("env %s is missing", name)
+				}
+			}
+
+			if tt.mount == "" {
+				return
+			}
+			var volumeName string
+			for _, mount := range container.VolumeMounts {
+				if mount.MountPath == tt.mount {
+					volumeName = mount.Name
+				}
+			}
+			if volumeName == "" {
+				t.Fatalf("nothing is mounted at %s", tt.mount)
+			}
+			for _, volume := range deployment.Spec.Template.Spec.Volumes {
+				if volume.Name != volumeName {
+					continue
+				}
+				if volume.Secret == nil || volume.Secret.SecretName != ref.Name {
+					t.Errorf("volume %s is not the Secret %s", volumeName, ref.Name)
+				}
+				return
+			}
+			t.Errorf("volume %s is missing", volumeName)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package components
+
+import (
+	"context"
+	"crypto/x509"
+	"slices"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// selfSignedTLS reports whether the operator generates the serving certificate of the registry.
+func selfSignedTLS(registry *registryoperatordevv1alpha1.Registry) bool {
+	return registry.Spec.TLS != nil && registry.Spec.TLS.SelfSigned
+}
+
+// renewalTime returns when a certificate is renewed, once two thirds of its validity passed.
+func renewalTime(certificate *x509.Certificate) time.Time {
+	validity := certificate.NotAfter.Sub(certificate.NotBefore)
+	return certificate.NotAfter.Add(-validity / 3)
+}
+
+// getSecret returns the Secret with the given name in the registry namespace, or nil if it does not exist.
+func (ro *RegistryOperations) getSecret(ctx context.Context, registry *registryoperatordevv1alpha1.Registry, name string) (*apiv1.Secret, error) {
+	secret := &apiv1.Secret{}
+	err := ro.Client.Get(ctx, client.ObjectKey{Namespace: registry.Namespace, Name: name}, secret)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return secret, err
+}
+
+// caRolloverPeriod is how long a renewed CA is published next to the current one before it signs the
+// serving certificate, and how long the replaced CA is still published after that. Clients trusting
+// the published bundle pick up the renewed CA before they are served a certificate it signed.
+const caRolloverPeriod = 30 * 24 * time.Hour
+
+// caSecretCertificate returns the certificate under the key of the CA Secret, or nil if it is missing or unparsable.
+func caSecretCertificate(ca *apiv1.Secret, key string) *x509.Certificate {
+	if ca == nil {
+		return nil
+	}
+	certificate, err := factories.ParseCertificate(ca.Data[key])
+	if err != nil {
+		return nil
+	}
+	return certificate
+}
+
+// caChangeTime returns when the CA Secret is due for a change. A missing or expired CA is replaced right away.
+// Once the CA is due for renewal, a renewed CA is published next to it and replaces it after caRolloverPeriod.
+// The replaced CA is published until twice caRolloverPeriod passed since the renewed one was issued.
+func caChangeTime(ca *apiv1.Secret) time.Time {
+	current := caSecretCertificate(ca, apiv1.TLSCertKey)
+	if current == nil {
+		return time.Time{}
+	}
+	changes := []time.Time{current.NotAfter}
+	if next := caSecretCertificate(ca, factories.NextCACertificateKey); next != nil {
+		changes = append(changes, next.NotBefore.Add(caRolloverPeriod))
+	} else {
+		changes = append(changes, renewalTime(current))
+	}
+	if previous := caSecretCertificate(ca, factories.PreviousCACertificateKey); previous != nil {
+		changes = append(changes, current.NotBefore.Add(2*caRolloverPeriod), previous.NotAfter)
+	}
+	return slices.MinFunc(changes, func(a, b time.Time) int { return a.Compare(b) })
+}
+
+// rolloverCA returns the CA Secret of the registry as it is due at the given time, see caChangeTime.
+// The ca.crt key holds the bundle of the current, the renewed and the replaced CA.
+func (ro *RegistryOperations) rolloverCA(
+	registry *registryoperatordevv1alpha1.Registry,
+	ca *apiv1.Secret,
+	now time.Time,
+) (*apiv1.Secret, error) {
+	current := caSecretCertificate(ca, apiv1.TLSCertKey)
+	next := caSecretCertificate(ca, factories.NextCACertificateKey)
+	if current == nil || (next == nil && !now.Before(current.NotAfter)) {
+		// There is no trusted CA to roll over from.
+		return ro.SecretFactory.NewCASecret(registry)
+	}
+
+	desired := ca.DeepCopy()
+	data := desired.Data
+	if next == nil {
+		delete(data, factories.NextCACertificateKey)
+		delete(data, factories.NextCAKeyKey)
+	} else if !now.Before(next.NotBefore.Add(caRolloverPeriod)) || !now.Before(current.NotAfter) {
+		data[factories.PreviousCACertificateKey] = data[apiv1.TLSCertKey]
+		data[apiv1.TLSCertKey] = data[factories.NextCACertificateKey]
+		data[apiv1.TLSPrivateKeyKey] = data[factories.NextCAKeyKey]
+		delete(data, factories.NextCACertificateKey)
+		delete(data, factories.NextCAKeyKey)
+		current, next = next, nil
+	}
+	previous := caSecretCertificate(desired, factories.PreviousCACertificateKey)
+	if previous == nil || !now.Before(current.NotBefore.Add(2*caRolloverPeriod)) || !now.Before(previous.NotAfter) {
+		delete(data, factories.PreviousCACertificateKey)
+	}
+	if next == nil && !now.Before(renewalTime(current)) {
+		renewed, err := ro.SecretFactory.NewCASecret(registry)
+		if err != nil {
+			return nil, err
+		}
+		data[factories.NextCACertificateKey] = renewed.Data[apiv1.TLSCertKey]
+		data[factories.NextCAKeyKey] = renewed.Data[apiv1.TLSPrivateKeyKey]
+	}
+
+	var bundle []byte
+	for _, key := range []string{apiv1.TLSCertKey, factories.NextCACertificateKey, factories.PreviousCACertificateKey} {
+		bundle = append(bundle, data[key]...)
+	}
+	data[factories.CACertificateKey] = bundle
+	return desired, nil
+}
+
+// validServingCertificate returns the certificate of the serving Secret, or nil if it is missing, unparsable,
+// due for renewal, not signed by the CA or not valid for the DNS names of the registry.
+func (ro *RegistryOperations) validServingCertificate(
+	registry *registryoperatordevv1alpha1.Registry,
+	secret *apiv1.Secret,
+	ca *x509.Certificate,
+	now time.Time,
+) *x509.Certificate {
+	if secret == nil || ca == nil {
+		return nil
+	}
+	certificate, err := factories.ParseCertificate(secret.Data[apiv1.TLSCertKey])
+	if err != nil || !now.Before(renewalTime(certificate)) || certificate.CheckSignatureFrom(ca) != nil {
+		return nil
+	}
+	if !slices.Equal(certificate.DNSNames, ro.SecretFactory.DNSNames(registry)) {
+		return nil
+	}
+	return certificate
+}
+
+// checkCertificateDrift reports whether the generated certificates of the registry or the published CA
+// are missing, outdated or due for renewal.
+func (ro *RegistryOperations) checkCertificateDrift(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (bool, error) {
+	if !selfSignedTLS(registry) {
+		return false, nil
+	}
+	now := time.Now()
+	ca, err := ro.getSecret(ctx, registry, factories.CASecretName(registry))
+	if err != nil {
+		return false, err
+	}
+	serving, err := ro.getSecret(ctx, registry, factories.TLSSecretName(registry))
+	if err != nil {
+		return false, err
+	}
+	if !now.Before(caChangeTime(ca)) {
+		return true, nil
+	}
+	if ro.validServingCertificate(registry, serving, caSecretCertificate(ca, apiv1.TLSCertKey), now) == nil {
+		return true, nil
+	}
+
+	desired := ro.ConfigMapFactory.NewCAConfigMap(registry, ca.Data[factories.CACertificateKey])
+	existing, exists, err := ro.getChild(ctx, desired)
+	if err != nil || !exists {
+		return !exists, err
+	}
+	return existing.(*apiv1.ConfigMap).Data[factories.CACertificateKey] != desired.Data[factories.CACertificateKey], nil
+}
+
+// applyCertificates generates the CA and the serving certificate of a registry with a self-signed certificate,
+// renewing them once two thirds of their validity passed, and publishes the CA bundle.
+// A renewed CA is published next to the current one for caRolloverPeriod before it signs the serving
+// certificate, so that clients trusting the bundle keep trusting the registry throughout the renewal.
+func (ro *RegistryOperations) applyCertificates(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) error {
+	l := log.FromContext(ctx)
+	if !selfSignedTLS(registry) {
+		return nil
+	}
+	now := time.Now()
+
+	ca, err := ro.getSecret(ctx, registry, factories.CASecretName(registry))
+	if err != nil {
+		return err
+	}
+	if !now.Before(caChangeTime(ca)) {
+		desired, err := ro.rolloverCA(registry, ca, now)
+		if err != nil {
+			return err
+		}
+		l.Info("Rolling over CA for", "registry", registry.Name)
+		if err = ro.createOrReplaceSecret(ctx, ca, desired); err != nil {
+			return err
+		}
+		ca = desired
+	}
+	caCertificate, err := factories.ParseCertificate(ca.Data[apiv1.TLSCertKey])
+	if err != nil {
+		return err
+	}
+
+	serving, err := ro.getSecret(ctx, registry, factories.TLSSecretName(registry))
+	if err != nil {
+		return err
+	}
+	if ro.validServingCertificate(registry, serving, caCertificate, now) == nil {
+		desired, err := ro.SecretFactory.NewServingCertificateSecret(registry, ca)
+		if err != nil {
+			return err
+		}
+		l.Info("Issuing serving certificate for", "registry", registry.Name)
+		if err = ro.createOrReplaceSecret(ctx, serving, desired); err != nil {
+			return err
+		}
+	}
+
+	desired := ro.ConfigMapFactory.NewCAConfigMap(registry, ca.Data[factories.CACertificateKey])
+	existing, exists, err := ro.getChild(ctx, desired)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		l.Info("Creating CA ConfigMap for", "registry", registry.Name)
+		return ro.Client.Create(ctx, desired)
+	}
+	if existing.(*apiv1.ConfigMap).Data[factories.CACertificateKey] == desired.Data[factories.CACertificateKey] {
+		return nil
+	}
+	l.Info("Updating CA ConfigMap for", "registry", registry.Name)
+	desired.SetResourceVersion(existing.GetResourceVersion())
+	return ro.Client.Update(ctx, desired)
+}
+
+// createOrReplaceSecret creates the desired Secret, or replaces the existing one when it is not nil.
+func (ro *RegistryOperations) createOrReplaceSecret(ctx context.Context, existing, desired *apiv1.Secret) error {
+	if existing == nil {
+		return ro.Client.Create(ctx, desired)
+	}
+	desired.SetResourceVersion(existing.GetResourceVersion())
+	return ro.Client.Update(ctx, desired)
+}
+
+// CertificateRenewalIn returns how long until a generated certificate of the registry is due for renewal,
+// or the CA Secret is due for a change. It is zero when the registry has no generated certificates.
+func (ro *RegistryOperations) CertificateRenewalIn(ctx context.Context, registry *registryoperatordevv1alpha1.Registry) (time.Duration, error) {
+	if !selfSignedTLS(registry) {
+		return 0, nil
+	}
+	ca, err := ro.getSecret(ctx, registry, factories.CASecretName(registry))
+	if err != nil || ca == nil {
+		return 0, err
+	}
+	serving, err := ro.getSecret(ctx, registry, factories.TLSSecretName(registry))
+	if err != nil || serving == nil {
+		return 0, err
+	}
+	certificate, err := factories.ParseCertificate(serving.Data[apiv1.TLSCertKey])
+	if err != nil {
+		return 0, err
+	}
+	next := caChangeTime(ca)
+	if renewal := renewalTime(certificate); renewal.Before(next) {
+		next = renewal
+	}
+	// Renew shortly after the renewal time, the certificates are still valid until well after it.
+	return max(time.Until(next), 0) + time.Minute, nil
+}
@@ -0,0 +1,208 @@
+package components
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// newTestCASecret returns a CA Secret as the operator generates it, valid from notBefore to notAfter.
+func newTestCASecret(t *testing.T, registry *registryoperatordevv1alpha1.Registry, notBefore, notAfter time.Time) *apiv1.Secret {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(notBefore.Unix()),
+		Subject:               pkix.Name{CommonName: "test registry CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: factories.CASecretName(registry), Namespace: registry.Namespace},
+		Type:       apiv1.SecretTypeTLS,
+		Data: map[string][]byte{
+			apiv1.TLSCertKey:           certificate,
+			apiv1.TLSPrivateKeyKey:     pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+			factories.CACertificateKey: certificate,
+		},
+	}
+}
+
+// bundleOf returns the concatenated certificates, as they are expected in a CA bundle.
+func bundleOf(certificates ...[]byte) string {
+	return string(bytes.Join(certificates, nil))
+}
+
+func TestCARollover(t *testing.T) {
+	ctx := context.Background()
+	registry := &registryoperatordevv1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default", UID: "uid"},
+		Spec: registryoperatordevv1alpha1.RegistrySpec{
+			Storage: registryoperatordevv1alpha1.Storage{Type: registryoperatordevv1alpha1.StorageTypeInMemory},
+			TLS:     &registryoperatordevv1alpha1.RegistryTLS{SelfSigned: true},
+		},
+	}
+	now := time.Now()
+
+	// A CA past two thirds of its validity, which signed the current serving certificate.
+	ca := newTestCASecret(t, registry, now.Add(-7*365*24*time.Hour), now.Add(3*365*24*time.Hour))
+	oldCA := ca.Data[apiv1.TLSCertKey]
+	ro := newTestRegistryOperations(t, registry, ca)
+	serving, err := ro.SecretFactory.NewServingCertificateSecret(registry, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ro.Client.Create(ctx, serving); err != nil {
+		t.Fatal(err)
+	}
+	if due := caChangeTime(ca); now.Before(due) {
+		t.Fatalf("CA change due at %s, want it due now", due)
+	}
+
+	getSecret := func(name string) *apiv1.Secret {
+		t.Helper()
+		secret := &apiv1.Secret{}
+		if err := ro.Client.Get(ctx, client.ObjectKey{Namespace: registry.Namespace, Name: name}, secret); err != nil {
+			t.Fatal(err)
+		}
+		return secret
+	}
+	expectBundle := func(want string) {
+		t.Helper()
+		configMap := &apiv1.ConfigMap{}
+		key := client.ObjectKey{Namespace: registry.Namespace, Name: factories.CAConfigMapName(registry)}
+		if err := ro.Client.Get(ctx, key, configMap); err != nil {
+			t.Fatal(err)
+		}
+		if configMap.Data[factories.CACertificateKey] != want {
+			t.Errorf("published CA bundle:\n%s\nwant:\n%s", configMap.Data[factories.CACertificateKey], want)
+		}
+	}
+
+	// The renewed CA is published next to the current one, which keeps signing the serving certificate.
+	if err := ro.applyCertificates(ctx, registry); err != nil {
+		t.Fatalf("applyCertificates() error = %v", err)
+	}
+	ca = getSecret(factories.CASecretName(registry))
+	newCA := ca.Data[factories.NextCACertificateKey]
+	if len(newCA) == 0 {
+		t.Fatal("no renewed CA was published")
+	}
+	if !bytes.Equal(ca.Data[apiv1.TLSCertKey], oldCA) {
+		t.Error("the CA was replaced before the renewed CA was published")
+	}
+	if !bytes.Equal(getSecret(serving.Name).Data[apiv1.TLSCertKey], serving.Data[apiv1.TLSCertKey]) {
+		t.Error("the serving certificate was reissued before the renewed CA was published")
+	}
+	expectBundle(bundleOf(oldCA, newCA))
+	if drift, err := ro.checkCertificateDrift(ctx, registry); err != nil || drift {
+		t.Errorf("checkCertificateDrift() = %t, %v, want no drift while the renewed CA is published", drift, err)
+	}
+	next := caSecretCertificate(ca, factories.NextCACertificateKey)
+	if due, want := caChangeTime(ca), next.NotBefore.Add(caRolloverPeriod); !due.Equal(want) {
+		t.Errorf("CA change due at %s, want %s", due, want)
+	}
+
+	// Once the rollover period passed, the renewed CA replaces the current one, which is still published.
+	rolledOver, err := ro.rolloverCA(registry, ca, now.Add(caRolloverPeriod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rolledOver.Data[apiv1.TLSCertKey], newCA) ||
+		!bytes.Equal(rolledOver.Data[apiv1.TLSPrivateKeyKey], ca.Data[factories.NextCAKeyKey]) {
+		t.Error("the renewed CA did not replace the current one")
+	}
+	if !bytes.Equal(rolledOver.Data[factories.PreviousCACertificateKey], oldCA) {
+		t.Error("the replaced CA is not kept")
+	}
+	if _, ok := rolledOver.Data[factories.NextCACertificateKey]; ok {
+		t.Error("the renewed CA is still kept as the next CA")
+	}
+	if err := ro.Client.Update(ctx, rolledOver); err != nil {
+		t.Fatal(err)
+	}
+
+	// The serving certificate is reissued by the renewed CA, both CAs stay published.
+	if drift, err := ro.checkCertificateDrift(ctx, registry); err != nil || !drift {
+		t.Errorf("checkCertificateDrift() = %t, %v, want drift once the CA was replaced", drift, err)
+	}
+	if err := ro.applyCertificates(ctx, registry); err != nil {
+		t.Fatalf("applyCertificates() error = %v", err)
+	}
+	reissued, err := factories.ParseCertificate(getSecret(serving.Name).Data[apiv1.TLSCertKey])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reissued.CheckSignatureFrom(next); err != nil {
+		t.Errorf("the serving certificate is not signed by the renewed CA: %v", err)
+	}
+	expectBundle(bundleOf(newCA, oldCA))
+
+	// After another rollover period, the replaced CA is no longer published.
+	retired, err := ro.rolloverCA(registry, rolledOver, now.Add(2*caRolloverPeriod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := retired.Data[factories.PreviousCACertificateKey]; ok {
+		t.Error("the replaced CA is still kept")
+	}
+	if got := string(retired.Data[factories.CACertificateKey]); got != bundleOf(newCA) {
+		t.Errorf("CA bundle:\n%s\nwant only the renewed CA", got)
+	}
+}
+
+func TestRolloverExpiredCA(t *testing.T) {
+	registry := &registryoperatordevv1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default", UID: "uid"},
+	}
+	now := time.Now()
+	expired := newTestCASecret(t, registry, now.Add(-10*365*24*time.Hour), now.Add(-time.Hour))
+	ro := newTestRegistryOperations(t)
+
+	if due := caChangeTime(expired); now.Before(due) {
+		t.Fatalf("CA change due at %s, want it due now", due)
+	}
+	replaced, err := ro.rolloverCA(registry, expired, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(replaced.Data[apiv1.TLSCertKey], expired.Data[apiv1.TLSCertKey]) {
+		t.Error("the expired CA was kept")
+	}
+	if got, want := string(replaced.Data[factories.CACertificateKey]), bundleOf(replaced.Data[apiv1.TLSCertKey]); got != want {
+		t.Errorf("CA bundle holds %d bytes, want only the new CA", len(got))
+	}
+
+	if due := caChangeTime(nil); now.Before(due) {
+		t.Errorf("CA change due at %s for a missing CA, want it due now", due)
+	}
+}
@@ -2,16 +2,28 @@ package controller
 
 import (
 	"context"
+	"slices"
 
 	"github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal"
 	"github.com/registry-operator/registry-operator/internal/components"
 	"github.com/registry-operator/registry-operator/internal/components/factories"
 	"github.com/registry-operator/registry-operator/internal/state"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 type RegistryReconciler struct {
@@ -23,14 +35,28 @@ type RegistryReconciler struct {
 }
 
 // newReconciler initializes a new RegistryReconciler with dependencies.
-func NewReconciler(client client.Client, scheme *runtime.Scheme) *RegistryReconciler {
+// The token authentication options are nil when the token issuer of the operator is disabled.
+func NewReconciler(client client.Client, scheme *runtime.Scheme, tokenAuth *factories.TokenAuthOptions) *RegistryReconciler {
 	return &RegistryReconciler{
 		Client:             client,
 		Scheme:             scheme,
-		RegistryOperations: components.NewRegistryOperations(client),
+		RegistryOperations: components.NewRegistryOperations(client, tokenAuth),
 	}
 }
 
+// +kubebuilder:rbac:groups=registry-operator.dev,resources=registries,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=registry-operator.dev,resources=registries/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=registry-operator.dev,resources=registries/finalizers,verbs=update
+// +kubebuilder:rbac:groups=registry-operator.dev,resources=registryaccesspolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=registry-operator.dev,resources=registryusers,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps;persistentvolumeclaims;secrets;services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=cronjobs;jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+
 // Reconcile is part of the main Kubernetes reconciliation loop.
 func (r *RegistryReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	l := log.FromContext(ctx)
@@ -46,6 +72,8 @@ func (r *RegistryReconciler) Reconcile(ctx context.Context, request reconcile.Re
 		handler = &state.Pending{RegistryOperations: r.RegistryOperations}
 	case v1alpha1.RegistryPhaseRunning:
 		handler = &state.Running{RegistryOperations: r.RegistryOperations}
+	case v1alpha1.RegistryPhaseUpdating:
+		handler = &state.Updating{RegistryOperations: r.RegistryOperations}
 	case v1alpha1.RegistryPhaseDeleting:
 		handler = &state.Deleting{RegistryOperations: r.RegistryOperations}
 	default:
@@ -57,7 +85,140 @@ func (r *RegistryReconciler) Reconcile(ctx context.Context, request reconcile.Re
 }
 
 func (r *RegistryReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	// Secrets and ConfigMaps are watched by their metadata only, so that their data is not cached cluster-wide.
+	// The manager reads them from the API server, see main.go.
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.Registry{}).
-		Complete(r)
+		Owns(&apiv1.ConfigMap{}, builder.OnlyMetadata).
+		Owns(&apiv1.Secret{}, builder.OnlyMetadata).
+		Owns(&apiv1.PersistentVolumeClaim{}).
+		Owns(&apiv1.Service{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&networkingv1.Ingress{}).
+		Owns(&batchv1.CronJob{}).
+		Watches(&apiv1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.registriesForSecret), builder.OnlyMetadata).
+		Watches(&apiv1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.registriesForConfigMap), builder.OnlyMetadata).
+		Watches(&apiv1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.registriesForNamespace)).
+		Watches(&v1alpha1.RegistryUser{}, handler.EnqueueRequestsFromMapFunc(r.registryForUser)).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(r.registryForJob))
+
+	// HTTPRoutes can only be watched when the Gateway API is installed in the cluster.
+	_, err := mgr.GetRESTMapper().RESTMapping(schema.GroupKind{Group: gatewayv1.GroupName, Kind: "HTTPRoute"}, gatewayv1.GroupVersion.Version)
+	switch {
+	case err == nil:
+		b = b.Owns(&gatewayv1.HTTPRoute{})
+	case meta.IsNoMatchError(err):
+		mgr.GetLogger().Info("Gateway API is not installed, HTTPRoutes are not watched")
+	default:
+		return err
+	}
+
+	return b.Complete(r)
+}
+
+// registryForUser maps a RegistryUser to the registry it references,
+// so that the htpasswd file of the registry follows its users.
+func (r *RegistryReconciler) registryForUser(_ context.Context, user client.Object) []reconcile.Request {
+	registryUser, ok := user.(*v1alpha1.RegistryUser)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: registryUser.Namespace,
+		Name:      registryUser.Spec.RegistryRef.Name,
+	}}}
+}
+
+// registryForJob maps a garbage collection Job to its registry,
+// so that the registry is turned read-only before the Job starts and writable again once it finished.
+func (r *RegistryReconciler) registryForJob(_ context.Context, job client.Object) []reconcile.Request {
+	labels := job.GetLabels()
+	if labels["app"] != "registry-garbage-collection" || labels["registry"] == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: job.GetNamespace(),
+		Name:      labels["registry"],
+	}}}
+}
+
+// distributingRegistry maps a copy distributed to another namespace to the registry it was copied for,
+// so that edited or deleted copies are restored.
+func distributingRegistry(obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	if labels[internal.RegistryNamespaceLabel] == "" || labels["registry"] == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: labels[internal.RegistryNamespaceLabel],
+		Name:      labels["registry"],
+	}}}
+}
+
+// registriesForConfigMap maps a ConfigMap to the registries in its namespace distributing it as their CA bundle,
+// or to the registry it was copied for.
+func (r *RegistryReconciler) registriesForConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	if requests := distributingRegistry(configMap); requests != nil {
+		return requests
+	}
+
+	l := log.FromContext(ctx)
+	registries := &v1alpha1.RegistryList{}
+	if err := r.List(ctx, registries, client.InNamespace(configMap.GetNamespace())); err != nil {
+		l.Error(err, "Failed to list registries", "namespace", configMap.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, registry := range registries.Items {
+		distribution := registry.Spec.Distribution
+		if distribution != nil && distribution.CAConfigMapRef != nil && distribution.CAConfigMapRef.Name == configMap.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&registry)})
+		}
+	}
+	return requests
+}
+
+// registriesForNamespace maps a Namespace to every registry distributing to namespaces,
+// as its labels may have started or stopped matching their selectors.
+func (r *RegistryReconciler) registriesForNamespace(ctx context.Context, _ client.Object) []reconcile.Request {
+	l := log.FromContext(ctx)
+	registries := &v1alpha1.RegistryList{}
+	if err := r.List(ctx, registries); err != nil {
+		l.Error(err, "Failed to list registries")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, registry := range registries.Items {
+		if registry.Spec.Distribution != nil {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&registry)})
+		}
+	}
+	return requests
+}
+
+// registriesForSecret maps a Secret to the registries in its namespace referencing it or named in its labels,
+// or to the registry it was copied for, so that changed credentials are rolled out to the registry.
+func (r *RegistryReconciler) registriesForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	if requests := distributingRegistry(secret); requests != nil {
+		return requests
+	}
+
+	l := log.FromContext(ctx)
+	registries := &v1alpha1.RegistryList{}
+	if err := r.List(ctx, registries, client.InNamespace(secret.GetNamespace())); err != nil {
+		l.Error(err, "Failed to list registries", "namespace", secret.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, registry := range registries.Items {
+		// Secrets of registry users carry the name of the registry in their labels.
+		if slices.Contains(components.ReferencedSecrets(&registry), secret.GetName()) ||
+			secret.GetLabels()["registry"] == registry.Name {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&registry)})
+		}
+	}
+	return requests
 }
@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// RegistryUserReconciler keeps the credentials Secret of registry users up to date and rotates their passwords.
+type RegistryUserReconciler struct {
+	client.Client
+	RegistryUserOperations *components.RegistryUserOperations
+}
+
+func NewRegistryUserReconciler(client client.Client) *RegistryUserReconciler {
+	return &RegistryUserReconciler{
+		Client:                 client,
+		RegistryUserOperations: components.NewRegistryUserOperations(client),
+	}
+}
+
+// +kubebuilder:rbac:groups=registry-operator.dev,resources=registryusers,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=registry-operator.dev,resources=registryusers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=registry-operator.dev,resources=registryusers/finalizers,verbs=update
+
+// Reconcile is part of the main Kubernetes reconciliation loop.
+func (r *RegistryUserReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	l := log.FromContext(ctx)
+	user := &v1alpha1.RegistryUser{}
+	if err := r.Get(ctx, request.NamespacedName, user); err != nil {
+		l.Info("Failed to get registry user", "error", err)
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	status := user.Status.DeepCopy()
+	now := time.Now()
+
+	registry, err := r.RegistryUserOperations.GetRegistry(ctx, user)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			l.Error(err, "Failed to get the registry", "name", user.Name)
+			return reconcile.Result{}, err
+		}
+		// The user is reconciled again once the registry is created.
+		setUserCondition(user, metav1.ConditionFalse, "RegistryNotFound", "Registry "+user.Spec.RegistryRef.Name+" does not exist")
+		return r.updateStatus(ctx, user, status, reconcile.Result{})
+	}
+
+	secret, err := r.RegistryUserOperations.GetRegistryUserSecret(ctx, user)
+	exists := err == nil
+	if err != nil && !apierrors.IsNotFound(err) {
+		l.Error(err, "Failed to get the Secret", "name", user.Name)
+		return reconcile.Result{}, err
+	}
+
+	// Generate a new password when requested, keep the current one otherwise,
+	// so that the Secret only follows changes of the registry hosts.
+	rotate := !exists || rotationDue(user, now)
+	password := ""
+	if exists {
+		password = string(secret.Data[apiv1.BasicAuthPasswordKey])
+	}
+	if rotate || password == "" {
+		rotate = true
+		password, err = factories.NewPassword()
+		if err != nil {
+			l.Error(err, "Failed to generate a password", "name", user.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
+	desired, err := r.RegistryUserOperations.SecretFactory.NewRegistryUserSecret(user, registry, password)
+	if err != nil {
+		l.Error(err, "Failed to render the Secret", "name", user.Name)
+		return reconcile.Result{}, err
+	}
+	switch {
+	case !exists:
+		err = r.RegistryUserOperations.CreateRegistryUserSecret(ctx, user, desired)
+	case rotate || !bytes.Equal(secret.Data[apiv1.DockerConfigJsonKey], desired.Data[apiv1.DockerConfigJsonKey]):
+		desired.SetResourceVersion(secret.GetResourceVersion())
+		err = r.RegistryUserOperations.UpdateRegistryUserSecret(ctx, user, desired)
+	}
+	if err != nil {
+		l.Error(err, "Failed to apply the Secret", "name", user.Name)
+		return reconcile.Result{}, err
+	}
+
+	user.Status.SecretName = desired.Name
+	if rotate {
+		user.Status.LastRotationTime = &metav1.Time{Time: now}
+		user.Status.ObservedRotation = user.Annotations[v1alpha1.RotateAnnotation]
+	}
+
+	switch {
+	case user.Spec.ExpiresAt != nil && !now.Before(user.Spec.ExpiresAt.Time):
+		setUserCondition(user, metav1.ConditionFalse, "Expired", "The user expired at "+user.Spec.ExpiresAt.UTC().Format(time.RFC3339))
+	case registry.Spec.Auth == nil || registry.Spec.Auth.Htpasswd == nil:
+		setUserCondition(user, metav1.ConditionFalse, "HtpasswdDisabled", "Registry "+registry.Name+" does not use htpasswd authentication")
+	default:
+		setUserCondition(user, metav1.ConditionTrue, "CredentialsIssued", "")
+	}
+
+	return r.updateStatus(ctx, user, status, reconcile.Result{RequeueAfter: nextTransition(user, now)})
+}
+
+// updateStatus updates the status of the user when it differs from the previous one.
+func (r *RegistryUserReconciler) updateStatus(
+	ctx context.Context,
+	user *v1alpha1.RegistryUser,
+	previous *v1alpha1.RegistryUserStatus,
+	result reconcile.Result,
+) (reconcile.Result, error) {
+	l := log.FromContext(ctx)
+	if equality.Semantic.DeepEqual(previous, &user.Status) {
+		return result, nil
+	}
+	err := r.RegistryUserOperations.UpdateRegistryUserStatus(ctx, user)
+	if err != nil {
+		l.Error(err, "Failed to update the registry user status", "name", user.Name)
+		return reconcile.Result{}, err
+	}
+	return result, nil
+}
+
+// setUserCondition records the Ready condition of the user.
+func setUserCondition(user *v1alpha1.RegistryUser, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&user.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionTypeReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: user.Generation,
+	})
+}
+
+// rotationDue reports whether the rotate annotation changed or the rotation interval elapsed.
+func rotationDue(user *v1alpha1.RegistryUser, now time.Time) bool {
+	if user.Annotations[v1alpha1.RotateAnnotation] != user.Status.ObservedRotation {
+		return true
+	}
+	interval := user.Spec.RotationInterval
+	last := user.Status.LastRotationTime
+	return interval != nil && interval.Duration > 0 && last != nil && !now.Before(last.Add(interval.Duration))
+}
+
+// nextTransition returns how long until the user has to be reconciled again to rotate its password or to expire.
+// It is zero when no such transition is ahead.
+func nextTransition(user *v1alpha1.RegistryUser, now time.Time) time.Duration {
+	var next time.Duration
+	consider := func(at time.Time) {
+		if until := at.Sub(now); until > 0 && (next == 0 || until < next) {
+			next = until
+		}
+	}
+	if interval := user.Spec.RotationInterval; interval != nil && interval.Duration > 0 && user.Status.LastRotationTime != nil {
+		consider(user.Status.LastRotationTime.Add(interval.Duration))
+	}
+	if user.Spec.ExpiresAt != nil {
+		consider(user.Spec.ExpiresAt.Time)
+	}
+	return next
+}
+
+func (r *RegistryUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.RegistryUser{}).
+		Owns(&apiv1.Secret{}, builder.OnlyMetadata).
+		Watches(&v1alpha1.Registry{}, handler.EnqueueRequestsFromMapFunc(r.usersForRegistry)).
+		Complete(r)
+}
+
+// usersForRegistry maps a Registry to the users referencing it, so that their Secrets follow the registry hosts.
+func (r *RegistryUserReconciler) usersForRegistry(ctx context.Context, registry client.Object) []reconcile.Request {
+	l := log.FromContext(ctx)
+	users := &v1alpha1.RegistryUserList{}
+	if err := r.List(ctx, users, client.InNamespace(registry.GetNamespace())); err != nil {
+		l.Error(err, "Failed to list registry users", "namespace", registry.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, user := range users.Items {
+		if user.Spec.RegistryRef.Name == registry.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&user)})
+		}
+	}
+	return requests
+}
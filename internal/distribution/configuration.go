@@ -0,0 +1,276 @@
+// Package distribution contains a model of the CNCF Distribution registry configuration.
+//
+// Only the subset of the configuration the operator renders is modeled. Field names follow
+// the configuration file format documented at https://distribution.github.io/distribution/about/configuration/.
+package distribution
+
+import (
+	"sigs.k8s.io/yaml"
+)
+
+// Version is the configuration file format version understood by the registry.
+const Version = "0.1"
+
+// Configuration is the root of the registry configuration file.
+type Configuration struct {
+	Version       string         `json:"version"`
+	Log           *Log           `json:"log,omitempty"`
+	Storage       Storage        `json:"storage"`
+	Auth          *Auth          `json:"auth,omitempty"`
+	HTTP          HTTP           `json:"http"`
+	Notifications *Notifications `json:"notifications,omitempty"`
+	Redis         *Redis         `json:"redis,omitempty"`
+	Health        *Health        `json:"health,omitempty"`
+	Proxy         *Proxy         `json:"proxy,omitempty"`
+	Validation    *Validation    `json:"validation,omitempty"`
+}
+
+// Marshal renders the configuration as YAML.
+func (c *Configuration) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// Log configures the behavior of the logging system.
+type Log struct {
+	Level     string            `json:"level,omitempty"`
+	Formatter string            `json:"formatter,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	AccessLog *AccessLog        `json:"accesslog,omitempty"`
+}
+
+// AccessLog configures the access logging.
+type AccessLog struct {
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// Storage configures the storage driver and the storage related behavior of the registry.
+// Exactly one of the driver fields should be set.
+type Storage struct {
+	InMemory   *InMemoryStorage   `json:"inmemory,omitempty"`
+	Filesystem *FilesystemStorage `json:"filesystem,omitempty"`
+	S3         *S3Storage         `json:"s3,omitempty"`
+	GCS        *GCSStorage        `json:"gcs,omitempty"`
+	Azure      *AzureStorage      `json:"azure,omitempty"`
+
+	Delete      *StorageDelete      `json:"delete,omitempty"`
+	Maintenance *StorageMaintenance `json:"maintenance,omitempty"`
+	Cache       *StorageCache       `json:"cache,omitempty"`
+	Redirect    *StorageRedirect    `json:"redirect,omitempty"`
+}
+
+// InMemoryStorage configures the inmemory storage driver, which has no parameters.
+type InMemoryStorage struct{}
+
+// FilesystemStorage configures the filesystem storage driver.
+type FilesystemStorage struct {
+	RootDirectory string `json:"rootdirectory,omitempty"`
+	MaxThreads    int    `json:"maxthreads,omitempty"`
+}
+
+// S3Storage configures the s3 storage driver.
+type S3Storage struct {
+	AccessKey      string `json:"accesskey,omitempty"`
+	SecretKey      string `json:"secretkey,omitempty"`
+	Region         string `json:"region"`
+	RegionEndpoint string `json:"regionendpoint,omitempty"`
+	Bucket         string `json:"bucket"`
+	Encrypt        bool   `json:"encrypt,omitempty"`
+	KeyID          string `json:"keyid,omitempty"`
+	Secure         *bool  `json:"secure,omitempty"`
+	RootDirectory  string `json:"rootdirectory,omitempty"`
+}
+
+// GCSStorage configures the gcs storage driver.
+type GCSStorage struct {
+	Bucket        string `json:"bucket"`
+	KeyFile       string `json:"keyfile,omitempty"`
+	RootDirectory string `json:"rootdirectory,omitempty"`
+	ChunkSize     int    `json:"chunksize,omitempty"`
+}
+
+// AzureStorage configures the azure storage driver.
+type AzureStorage struct {
+	AccountName string `json:"accountname"`
+	AccountKey  string `json:"accountkey,omitempty"`
+	Container   string `json:"container"`
+	Realm       string `json:"realm,omitempty"`
+}
+
+// StorageDelete configures deletion of image blobs and manifests.
+type StorageDelete struct {
+	Enabled bool `json:"enabled"`
+}
+
+// StorageMaintenance configures the upload purging and read-only modes.
+type StorageMaintenance struct {
+	UploadPurging *UploadPurging `json:"uploadpurging,omitempty"`
+	ReadOnly      *ReadOnly      `json:"readonly,omitempty"`
+}
+
+// UploadPurging configures the background removal of abandoned uploads.
+type UploadPurging struct {
+	Enabled  bool   `json:"enabled"`
+	Age      string `json:"age,omitempty"`
+	Interval string `json:"interval,omitempty"`
+	DryRun   bool   `json:"dryrun"`
+}
+
+// ReadOnly configures the read-only maintenance mode.
+type ReadOnly struct {
+	Enabled bool `json:"enabled"`
+}
+
+// StorageCache configures the cache of blob descriptors.
+type StorageCache struct {
+	BlobDescriptor string `json:"blobdescriptor,omitempty"`
+}
+
+// StorageRedirect configures redirects to the storage backend.
+type StorageRedirect struct {
+	Disable bool `json:"disable"`
+}
+
+// Auth configures the access controller. Only one of the fields should be set.
+type Auth struct {
+	Htpasswd *HtpasswdAuth `json:"htpasswd,omitempty"`
+	Token    *TokenAuth    `json:"token,omitempty"`
+}
+
+// HtpasswdAuth configures basic authentication against an htpasswd file.
+type HtpasswdAuth struct {
+	Realm string `json:"realm"`
+	Path  string `json:"path"`
+}
+
+// TokenAuth configures token based authentication delegated to an external token issuer.
+type TokenAuth struct {
+	Realm          string `json:"realm"`
+	Service        string `json:"service"`
+	Issuer         string `json:"issuer"`
+	RootCertBundle string `json:"rootcertbundle"`
+	AutoRedirect   bool   `json:"autoredirect,omitempty"`
+}
+
+// HTTP configures the HTTP server that hosts the registry.
+type HTTP struct {
+	Addr         string              `json:"addr"`
+	Net          string              `json:"net,omitempty"`
+	Prefix       string              `json:"prefix,omitempty"`
+	Host         string              `json:"host,omitempty"`
+	Secret       string              `json:"secret,omitempty"`
+	RelativeURLs bool                `json:"relativeurls,omitempty"`
+	DrainTimeout string              `json:"draintimeout,omitempty"`
+	Headers      map[string][]string `json:"headers,omitempty"`
+	TLS          *TLS                `json:"tls,omitempty"`
+	Debug        *Debug              `json:"debug,omitempty"`
+}
+
+// TLS configures the TLS certificate served by the registry.
+type TLS struct {
+	Certificate string   `json:"certificate"`
+	Key         string   `json:"key"`
+	ClientCAs   []string `json:"clientcas,omitempty"`
+}
+
+// Debug configures the debug server of the registry.
+type Debug struct {
+	Addr       string      `json:"addr"`
+	Prometheus *Prometheus `json:"prometheus,omitempty"`
+}
+
+// Prometheus configures the metrics endpoint of the debug server.
+type Prometheus struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path,omitempty"`
+}
+
+// Notifications configures the webhook notifications emitted by the registry.
+type Notifications struct {
+	Events    *Events    `json:"events,omitempty"`
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+}
+
+// Events configures the content of the emitted events.
+type Events struct {
+	IncludeReferences bool `json:"includereferences,omitempty"`
+}
+
+// Endpoint configures a single notification endpoint.
+type Endpoint struct {
+	Name              string              `json:"name"`
+	Disabled          bool                `json:"disabled,omitempty"`
+	URL               string              `json:"url"`
+	Headers           map[string][]string `json:"headers,omitempty"`
+	Timeout           string              `json:"timeout,omitempty"`
+	Threshold         int                 `json:"threshold,omitempty"`
+	Backoff           string              `json:"backoff,omitempty"`
+	IgnoredMediaTypes []string            `json:"ignoredmediatypes,omitempty"`
+	Ignore            *Ignore             `json:"ignore,omitempty"`
+}
+
+// Ignore configures the events that are not sent to an endpoint.
+type Ignore struct {
+	MediaTypes []string `json:"mediatypes,omitempty"`
+	Actions    []string `json:"actions,omitempty"`
+}
+
+// Redis configures the connection to a redis instance used for caching.
+type Redis struct {
+	Addr         string     `json:"addr"`
+	Password     string     `json:"password,omitempty"`
+	DB           int        `json:"db,omitempty"`
+	DialTimeout  string     `json:"dialtimeout,omitempty"`
+	ReadTimeout  string     `json:"readtimeout,omitempty"`
+	WriteTimeout string     `json:"writetimeout,omitempty"`
+	Pool         *RedisPool `json:"pool,omitempty"`
+	TLS          *RedisTLS  `json:"tls,omitempty"`
+}
+
+// RedisPool configures the redis connection pool.
+type RedisPool struct {
+	MaxIdle     int    `json:"maxidle,omitempty"`
+	MaxActive   int    `json:"maxactive,omitempty"`
+	IdleTimeout string `json:"idletimeout,omitempty"`
+}
+
+// RedisTLS configures TLS for the redis connection.
+type RedisTLS struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Health configures the health checks of the registry.
+type Health struct {
+	StorageDriver *StorageDriverHealth `json:"storagedriver,omitempty"`
+}
+
+// StorageDriverHealth configures the health check of the storage driver.
+type StorageDriverHealth struct {
+	Enabled   bool   `json:"enabled"`
+	Interval  string `json:"interval,omitempty"`
+	Threshold int    `json:"threshold,omitempty"`
+}
+
+// Proxy configures the registry as a pull-through cache.
+type Proxy struct {
+	RemoteURL string `json:"remoteurl"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+	TTL       string `json:"ttl,omitempty"`
+}
+
+// Validation configures the validation of pushed manifests.
+type Validation struct {
+	Disabled  bool                 `json:"disabled,omitempty"`
+	Manifests *ManifestsValidation `json:"manifests,omitempty"`
+}
+
+// ManifestsValidation configures the validation of manifests.
+type ManifestsValidation struct {
+	URLs *URLsValidation `json:"urls,omitempty"`
+}
+
+// URLsValidation configures the allowed and denied foreign layer URLs.
+type URLsValidation struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
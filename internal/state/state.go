@@ -5,6 +5,8 @@ import (
 
 	"github.com/registry-operator/registry-operator/api/v1alpha1"
 	"github.com/registry-operator/registry-operator/internal/components"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -13,7 +15,8 @@ type Handler interface {
 	Handle(ctx context.Context, registry *v1alpha1.Registry) (reconcile.Result, error)
 }
 
-// Pending ---Pod creation---> Running.
+// Pending ---Deployment creation---> Running.
+// Pending ---Registry deletion---> Deleting.
 type Pending struct {
 	RegistryOperations *components.RegistryOperations
 }
@@ -21,100 +24,257 @@ type Pending struct {
 func (s *Pending) Handle(ctx context.Context, registry *v1alpha1.Registry) (reconcile.Result, error) {
 	l := log.FromContext(ctx)
 
-	if registry.Spec.Storage.Type == "" {
-		// We got registry before defaults were applied.
-		// We should requeue the request to get the registry with defaults.
-		return reconcile.Result{Requeue: true}, nil
+	if !registry.DeletionTimestamp.IsZero() {
+		// A registry that never rolled out is deleted as well, move to the Deleting state.
+		registry.Status.Phase = v1alpha1.RegistryPhaseDeleting
+		err := s.RegistryOperations.UpdateRegistryStatus(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to update the registry status", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
 	}
 
-	// Create the ConfigMap for the registry if it doesn't exist.
-	exists, err := s.RegistryOperations.CheckRegistryConfigMapExists(ctx, registry)
+	// Defaults are applied on admission. Specs that cannot be turned into a registry, which only get here
+	// when the webhooks are disabled, are reported instead of failing on every child resource.
+	err := s.RegistryOperations.ValidateRegistryConfig(registry)
 	if err != nil {
-		l.Error(err, "Failed to check if the ConfigMap exists", "name", registry.Name)
+		l.Error(err, "Invalid registry configuration", "name", registry.Name)
+		setInvalidConfigStatus(registry, err)
+		err = s.RegistryOperations.UpdateRegistryStatus(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to update the registry status", "name", registry.Name)
+		}
 		return reconcile.Result{}, err
 	}
 
-	if !exists {
-		err = s.RegistryOperations.CreateRegistryConfigMap(ctx, registry)
-		if err != nil {
-			l.Error(err, "Failed to create the ConfigMap", "name", registry.Name)
-			return reconcile.Result{}, err
+	// Create the child resources of the registry.
+	err = s.RegistryOperations.ApplyRegistryChanges(ctx, registry)
+	if err != nil {
+		l.Error(err, "Failed to create the registry resources", "name", registry.Name)
+		return reconcile.Result{}, err
+	}
+
+	// Add finalizer to the registry.
+	err = s.RegistryOperations.AddFinalizer(ctx, registry)
+	if err != nil {
+		l.Error(err, "Failed to add finalizer to the registry", "name", registry.Name)
+		return reconcile.Result{}, err
+	}
+
+	// Once the Deployment is rolled out, move to the Running state.
+	rolledOut, err := refreshStatus(ctx, s.RegistryOperations, registry)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// The Deployment was just created and is not in the cache yet.
+			return reconcile.Result{RequeueAfter: requeueInterval}, nil
 		}
+		l.Error(err, "Failed to refresh the registry status", "name", registry.Name)
+		return reconcile.Result{}, err
 	}
 
-	// Create the pod for the registry if it doesn't exist.
-	exists, err = s.RegistryOperations.CheckRegistryPodExists(ctx, registry)
+	if rolledOut {
+		registry.Status.Phase = v1alpha1.RegistryPhaseRunning
+	}
+	err = s.RegistryOperations.UpdateRegistryStatus(ctx, registry)
 	if err != nil {
-		l.Error(err, "Failed to check if the pod exists", "name", registry.Name)
+		l.Error(err, "Failed to update the registry status", "name", registry.Name)
 		return reconcile.Result{}, err
 	}
 
-	if exists {
-		// Add finalizer to the registry.
-		err = s.RegistryOperations.AddFinalizer(ctx, registry)
+	if !rolledOut {
+		return reconcile.Result{RequeueAfter: requeueInterval}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// Running ---Spec change---> Updating.
+// Running ---Registry deletion---> Deleting.
+type Running struct {
+	RegistryOperations *components.RegistryOperations
+}
+
+func (s *Running) Handle(ctx context.Context, registry *v1alpha1.Registry) (reconcile.Result, error) {
+	l := log.FromContext(ctx)
+
+	if registry.DeletionTimestamp.IsZero() {
+		observed := registry.Status.DeepCopy()
+
+		// Pending and running garbage collection Jobs turn the registry read-only.
+		err := s.RegistryOperations.RefreshGarbageCollection(ctx, registry)
 		if err != nil {
-			l.Error(err, "Failed to add finalizer to the registry", "name", registry.Name)
+			l.Error(err, "Failed to check the garbage collection of the registry", "name", registry.Name)
 			return reconcile.Result{}, err
 		}
 
-		// If the pod already exists, move to the Running state.
-		registry.Status.Phase = v1alpha1.RegistryPhaseRunning
+		// Child resources carry the hash of the spec they were rendered from,
+		// a mismatch means the spec changed since they were applied.
+		drift, err := s.RegistryOperations.CheckRegistryDrift(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to check the registry for configuration drift", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
+		if !drift {
+			result, err := s.scale(ctx, registry, observed)
+			if err != nil {
+				return result, err
+			}
+			// Come back in time to renew the certificates generated for the registry.
+			result.RequeueAfter, err = s.RegistryOperations.CertificateRenewalIn(ctx, registry)
+			if err != nil {
+				l.Error(err, "Failed to check the registry certificates", "name", registry.Name)
+			}
+			return result, err
+		}
+
+		// Correct the child resources right away, the Updating state waits for the rollout.
+		err = s.RegistryOperations.ApplyRegistryChanges(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to apply changes to the registry resources", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
+
+		registry.Status.Phase = v1alpha1.RegistryPhaseUpdating
 		err = s.RegistryOperations.UpdateRegistryStatus(ctx, registry)
 		if err != nil {
 			l.Error(err, "Failed to update the registry status", "name", registry.Name)
 			return reconcile.Result{}, err
 		}
-		return reconcile.Result{}, nil
+		return reconcile.Result{RequeueAfter: requeueInterval}, nil
 	}
 
-	// If the pod doesn't exist, create it.
-	err = s.RegistryOperations.CreateRegistryPod(ctx, registry)
+	// If the registry is being deleted, move to the Deleting state.
+	registry.Status.Phase = v1alpha1.RegistryPhaseDeleting
+	err := s.RegistryOperations.UpdateRegistryStatus(ctx, registry)
 	if err != nil {
-		l.Error(err, "Failed to create or update the pod", "name", registry.Name)
+		l.Error(err, "Failed to update the registry status", "name", registry.Name)
 		return reconcile.Result{}, err
 	}
 
-	// Add finalizer to the registry.
-	err = s.RegistryOperations.AddFinalizer(ctx, registry)
+	return reconcile.Result{}, nil
+}
+
+// scale propagates the desired number of replicas to the Deployment, starts the pending garbage collection
+// once the registry is rolled out and reports the observed state in the status.
+func (s *Running) scale(ctx context.Context, registry *v1alpha1.Registry, observed *v1alpha1.RegistryStatus) (reconcile.Result, error) {
+	l := log.FromContext(ctx)
+
+	deployment, err := s.RegistryOperations.GetRegistryDeployment(ctx, registry)
 	if err != nil {
-		l.Error(err, "Failed to add finalizer to the registry", "name", registry.Name)
+		l.Error(err, "Failed to get the Deployment", "name", registry.Name)
 		return reconcile.Result{}, err
 	}
 
-	// If the pod is created, move to the Running state.
-	registry.Status.Phase = v1alpha1.RegistryPhaseRunning
-	err = s.RegistryOperations.UpdateRegistryStatus(ctx, registry)
+	if registry.Spec.Replicas != nil &&
+		(deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != *registry.Spec.Replicas) {
+		deployment.Spec.Replicas = registry.Spec.Replicas
+		err = s.RegistryOperations.UpdateRegistryDeployment(ctx, registry, deployment)
+		if err != nil {
+			l.Error(err, "Failed to scale the Deployment", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
+	rolledOut, err := refreshStatus(ctx, s.RegistryOperations, registry)
 	if err != nil {
-		l.Error(err, "Failed to update the registry status", "name", registry.Name)
+		l.Error(err, "Failed to refresh the registry status", "name", registry.Name)
 		return reconcile.Result{}, err
 	}
 
+	// Every pod serves the read-only configuration once the registry is rolled out.
+	if rolledOut {
+		err = s.RegistryOperations.ResumeGarbageCollection(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to start the garbage collection", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(observed, &registry.Status) {
+		err = s.RegistryOperations.UpdateRegistryStatus(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to update the registry status", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
 	return reconcile.Result{}, nil
 }
 
-// Running ---Registry deletion---> Deleting.
-type Running struct {
+// Updating ---Rollout of the changed spec---> Running.
+// Updating ---Registry deletion---> Deleting.
+type Updating struct {
 	RegistryOperations *components.RegistryOperations
 }
 
-func (s *Running) Handle(ctx context.Context, registry *v1alpha1.Registry) (reconcile.Result, error) {
+func (s *Updating) Handle(ctx context.Context, registry *v1alpha1.Registry) (reconcile.Result, error) {
 	l := log.FromContext(ctx)
 
-	if registry.DeletionTimestamp.IsZero() {
-		// Here we could check if there were configuration changes and add states for updates to child resources.
-		// We could compare checksum of current registry spec with checksum of registry spec
-		// that was generated and saved on resources annotation when they were being first created.
+	if !registry.DeletionTimestamp.IsZero() {
+		// If the registry is being deleted, move to the Deleting state.
+		registry.Status.Phase = v1alpha1.RegistryPhaseDeleting
+		err := s.RegistryOperations.UpdateRegistryStatus(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to update the registry status", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
 		return reconcile.Result{}, nil
 	}
 
-	// If the registry is being deleted, move to the Deleting state.
-	registry.Status.Phase = v1alpha1.RegistryPhaseDeleting
-	err := s.RegistryOperations.UpdateRegistryStatus(ctx, registry)
+	// An edit may have turned the spec into one that cannot be rendered.
+	err := s.RegistryOperations.ValidateRegistryConfig(registry)
+	if err != nil {
+		l.Error(err, "Invalid registry configuration", "name", registry.Name)
+		setInvalidConfigStatus(registry, err)
+		err = s.RegistryOperations.UpdateRegistryStatus(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to update the registry status", "name", registry.Name)
+		}
+		return reconcile.Result{}, err
+	}
+
+	err = s.RegistryOperations.RefreshGarbageCollection(ctx, registry)
+	if err != nil {
+		l.Error(err, "Failed to check the garbage collection of the registry", "name", registry.Name)
+		return reconcile.Result{}, err
+	}
+
+	drift, err := s.RegistryOperations.CheckRegistryDrift(ctx, registry)
+	if err != nil {
+		l.Error(err, "Failed to check the registry for configuration drift", "name", registry.Name)
+		return reconcile.Result{}, err
+	}
+
+	if drift {
+		// Apply the changed spec and come back once the cache reflects it,
+		// so that the rollout is not judged on the previous Deployment.
+		err = s.RegistryOperations.ApplyRegistryChanges(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to apply changes to the registry resources", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{RequeueAfter: requeueInterval}, nil
+	}
+
+	// Once the changed Deployment is rolled out, move back to the Running state.
+	rolledOut, err := refreshStatus(ctx, s.RegistryOperations, registry)
+	if err != nil {
+		l.Error(err, "Failed to refresh the registry status", "name", registry.Name)
+		return reconcile.Result{}, err
+	}
+
+	if rolledOut {
+		registry.Status.Phase = v1alpha1.RegistryPhaseRunning
+	}
+	err = s.RegistryOperations.UpdateRegistryStatus(ctx, registry)
 	if err != nil {
 		l.Error(err, "Failed to update the registry status", "name", registry.Name)
 		return reconcile.Result{}, err
 	}
 
+	if !rolledOut {
+		return reconcile.Result{RequeueAfter: requeueInterval}, nil
+	}
 	return reconcile.Result{}, nil
 }
 
@@ -138,29 +298,17 @@ func (s *Deleting) Handle(ctx context.Context, registry *v1alpha1.Registry) (rec
 		return reconcile.Result{}, nil
 	}
 
-	// This block will probably be something reoccuring for every resoure that we have to delete.
-	// It may be a good idea to extract this to a separate function if it happens.
-	{
-		// Delete the pod for the registry.
-		exists, err = s.RegistryOperations.CheckRegistryPodExists(ctx, registry)
-		if err != nil {
-			l.Error(err, "Failed to check if the pod exists", "name", registry.Name)
-			return reconcile.Result{}, err
-		}
-
-		if !exists {
-			// If the pod doesn't exist, remove the finalizer and finish the deletion.
-			err = s.RegistryOperations.RemoveFinalizer(ctx, registry)
-			if err != nil {
-				l.Error(err, "Failed to remove finalizer from the registry", "name", registry.Name)
-				return reconcile.Result{}, err
-			}
-			return reconcile.Result{}, nil
-		}
+	// Delete the Deployment for the registry.
+	exists, err = s.RegistryOperations.CheckRegistryDeploymentExists(ctx, registry)
+	if err != nil {
+		l.Error(err, "Failed to check if the Deployment exists", "name", registry.Name)
+		return reconcile.Result{}, err
+	}
 
-		err = s.RegistryOperations.DeleteRegistryPod(ctx, registry)
+	if exists {
+		err = s.RegistryOperations.DeleteRegistryDeployment(ctx, registry)
 		if err != nil {
-			l.Error(err, "Failed to delete the pod", "name", registry.Name)
+			l.Error(err, "Failed to delete the Deployment", "name", registry.Name)
 			return reconcile.Result{}, err
 		}
 	}
@@ -180,6 +328,73 @@ func (s *Deleting) Handle(ctx context.Context, registry *v1alpha1.Registry) (rec
 		}
 	}
 
+	// Delete the Service for the registry.
+	exists, err = s.RegistryOperations.CheckRegistryServiceExists(ctx, registry)
+	if err != nil {
+		l.Error(err, "Failed to check if the Service exists", "name", registry.Name)
+		return reconcile.Result{}, err
+	}
+
+	if exists {
+		err = s.RegistryOperations.DeleteRegistryService(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to delete the Service", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Delete the Ingress and the HTTPRoute exposing the registry.
+	exists, err = s.RegistryOperations.CheckRegistryIngressExists(ctx, registry)
+	if err != nil {
+		l.Error(err, "Failed to check if the Ingress exists", "name", registry.Name)
+		return reconcile.Result{}, err
+	}
+
+	if exists {
+		err = s.RegistryOperations.DeleteRegistryIngress(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to delete the Ingress", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
+	exists, err = s.RegistryOperations.CheckRegistryHTTPRouteExists(ctx, registry)
+	if err != nil {
+		l.Error(err, "Failed to check if the HTTPRoute exists", "name", registry.Name)
+		return reconcile.Result{}, err
+	}
+
+	if exists {
+		err = s.RegistryOperations.DeleteRegistryHTTPRoute(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to delete the HTTPRoute", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Delete the PersistentVolumeClaim for the registry.
+	exists, err = s.RegistryOperations.CheckRegistryPersistentVolumeClaimExists(ctx, registry)
+	if err != nil {
+		l.Error(err, "Failed to check if the PersistentVolumeClaim exists", "name", registry.Name)
+		return reconcile.Result{}, err
+	}
+
+	if exists {
+		err = s.RegistryOperations.DeleteRegistryPersistentVolumeClaim(ctx, registry)
+		if err != nil {
+			l.Error(err, "Failed to delete the PersistentVolumeClaim", "name", registry.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Delete the CA bundles and pull secrets distributed to other namespaces,
+	// they are not owned by the registry and would outlive it.
+	err = s.RegistryOperations.DeleteDistributedCopies(ctx, registry)
+	if err != nil {
+		l.Error(err, "Failed to delete the distributed copies", "name", registry.Name)
+		return reconcile.Result{}, err
+	}
+
 	// Remove the finalizer from the registry.
 	err = s.RegistryOperations.RemoveFinalizer(ctx, registry)
 	if err != nil {
@@ -0,0 +1,77 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal"
+	"github.com/registry-operator/registry-operator/internal/components"
+)
+
+func TestPendingDeletion(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		gatewayv1.AddToScheme,
+		v1alpha1.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A registry that never rolled out, deleted while it still carries the finalizer.
+	deleted := metav1.Now()
+	registry := &v1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "registry",
+			Namespace:         "default",
+			UID:               "uid",
+			Finalizers:        []string{internal.RegistryFinalizer},
+			DeletionTimestamp: &deleted,
+		},
+		Spec: v1alpha1.RegistrySpec{
+			Storage: v1alpha1.Storage{Type: v1alpha1.StorageTypeInMemory},
+		},
+		Status: v1alpha1.RegistryStatus{Phase: v1alpha1.RegistryPhasePending},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(registry).
+		WithStatusSubresource(registry).
+		Build()
+	ro := components.NewRegistryOperations(c, nil)
+
+	if _, err := (&Pending{RegistryOperations: ro}).Handle(ctx, registry); err != nil {
+		t.Fatalf("Pending.Handle() error = %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(registry), registry); err != nil {
+		t.Fatal(err)
+	}
+	if registry.Status.Phase != v1alpha1.RegistryPhaseDeleting {
+		t.Errorf("phase = %q, want %q", registry.Status.Phase, v1alpha1.RegistryPhaseDeleting)
+	}
+	err := c.Get(ctx, client.ObjectKeyFromObject(registry), &appsv1.Deployment{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("the Deployment of a deleted registry was created, error = %v", err)
+	}
+
+	if _, err := (&Deleting{RegistryOperations: ro}).Handle(ctx, registry); err != nil {
+		t.Fatalf("Deleting.Handle() error = %v", err)
+	}
+	err = c.Get(ctx, client.ObjectKeyFromObject(registry), &v1alpha1.Registry{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("the registry still exists once its finalizer ran, error = %v", err)
+	}
+}
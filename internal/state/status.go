@@ -0,0 +1,156 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// requeueInterval is how often a registry is reconciled while waiting for its workload.
+const requeueInterval = 5 * time.Second
+
+// setCondition records a condition observed for the current generation of the registry.
+func setCondition(registry *v1alpha1.Registry, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&registry.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: registry.Generation,
+	})
+}
+
+// setInvalidConfigStatus reports a spec the operator cannot turn into a working registry.
+func setInvalidConfigStatus(registry *v1alpha1.Registry, err error) {
+	registry.Status.ObservedGeneration = registry.Generation
+	setCondition(registry, v1alpha1.ConditionTypeConfigValid, metav1.ConditionFalse, "InvalidConfiguration", err.Error())
+	setCondition(registry, v1alpha1.ConditionTypeReady, metav1.ConditionFalse, "InvalidConfiguration", err.Error())
+	setCondition(registry, v1alpha1.ConditionTypeDegraded, metav1.ConditionTrue, "InvalidConfiguration", err.Error())
+}
+
+// refreshStatus updates the registry status from its child resources.
+// It returns whether the registry Deployment finished rolling out.
+func refreshStatus(ctx context.Context, ro *components.RegistryOperations, registry *v1alpha1.Registry) (bool, error) {
+	deployment, err := ro.GetRegistryDeployment(ctx, registry)
+	if err != nil {
+		return false, err
+	}
+
+	var pvc *apiv1.PersistentVolumeClaim
+	if registry.Spec.Storage.Type == v1alpha1.StorageTypeFilesystem {
+		pvc, err = ro.GetRegistryPersistentVolumeClaim(ctx, registry)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	configHash, err := ro.ConfigHash(registry)
+	if err != nil {
+		return false, err
+	}
+
+	registry.Status.ObservedGeneration = registry.Generation
+	registry.Status.ConfigHash = configHash
+	registry.Status.Selector = factories.SelectorLabels(registry)
+	registry.Status.URL = ro.RegistryURL(registry)
+	registry.Status.ExternalURL = factories.ExternalURL(registry)
+	setCondition(registry, v1alpha1.ConditionTypeConfigValid, metav1.ConditionTrue, "ConfigRendered", "")
+	setStorageStatus(registry, pvc)
+	setReadOnlyStatus(registry)
+	return setWorkloadStatus(registry, deployment), nil
+}
+
+// setStorageStatus sets the StorageReady condition. The claim is nil for storage types without one.
+func setStorageStatus(registry *v1alpha1.Registry, pvc *apiv1.PersistentVolumeClaim) {
+	switch {
+	case registry.Spec.Storage.Type == v1alpha1.StorageTypeInMemory:
+		setCondition(registry, v1alpha1.ConditionTypeStorageReady, metav1.ConditionTrue, "InMemory",
+			"Registry data is lost when the pods restart")
+	case pvc == nil:
+		setCondition(registry, v1alpha1.ConditionTypeStorageReady, metav1.ConditionTrue, "ObjectStorageConfigured", "")
+	case pvc.Status.Phase == apiv1.ClaimBound:
+		setCondition(registry, v1alpha1.ConditionTypeStorageReady, metav1.ConditionTrue, "ClaimBound", "")
+	default:
+		setCondition(registry, v1alpha1.ConditionTypeStorageReady, metav1.ConditionFalse, "ClaimPending",
+			fmt.Sprintf("PersistentVolumeClaim %s is %s", pvc.Name, pvc.Status.Phase))
+	}
+}
+
+// setReadOnlyStatus sets the ReadOnly condition from the configuration the registry is rolled out with.
+func setReadOnlyStatus(registry *v1alpha1.Registry) {
+	switch {
+	case registry.Spec.Maintenance != nil && registry.Spec.Maintenance.ReadOnly:
+		setCondition(registry, v1alpha1.ConditionTypeReadOnly, metav1.ConditionTrue, "MaintenanceMode",
+			"Pushes and deletes are rejected until spec.maintenance.readOnly is unset")
+	case registry.Status.GarbageCollectionJob != "":
+		setCondition(registry, v1alpha1.ConditionTypeReadOnly, metav1.ConditionTrue, "GarbageCollection",
+			fmt.Sprintf("Pushes and deletes are rejected until Job %s finished", registry.Status.GarbageCollectionJob))
+	default:
+		setCondition(registry, v1alpha1.ConditionTypeReadOnly, metav1.ConditionFalse, "Writable", "")
+	}
+}
+
+// setWorkloadStatus sets the status fields and conditions derived from the registry Deployment.
+// It returns whether the Deployment finished rolling out.
+func setWorkloadStatus(registry *v1alpha1.Registry, deployment *appsv1.Deployment) bool {
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+
+	registry.Status.Replicas = status.Replicas
+	if containers := deployment.Spec.Template.Spec.Containers; len(containers) > 0 {
+		registry.Status.Image = containers[0].Image
+	}
+
+	rolledOut := status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas == desired &&
+		status.Replicas == desired &&
+		status.AvailableReplicas == desired
+	availability := fmt.Sprintf("%d/%d replicas available", status.AvailableReplicas, desired)
+
+	if rolledOut {
+		setCondition(registry, v1alpha1.ConditionTypeProgressing, metav1.ConditionFalse, "RolloutComplete", "")
+	} else {
+		setCondition(registry, v1alpha1.ConditionTypeProgressing, metav1.ConditionTrue, "RollingOut", availability)
+	}
+
+	switch {
+	case desired == 0:
+		setCondition(registry, v1alpha1.ConditionTypeReady, metav1.ConditionFalse, "ScaledToZero", availability)
+	case rolledOut:
+		setCondition(registry, v1alpha1.ConditionTypeReady, metav1.ConditionTrue, "Available", availability)
+	default:
+		setCondition(registry, v1alpha1.ConditionTypeReady, metav1.ConditionFalse, "Unavailable", availability)
+	}
+
+	if reason, message, failed := deploymentFailure(deployment); failed {
+		setCondition(registry, v1alpha1.ConditionTypeDegraded, metav1.ConditionTrue, reason, message)
+	} else {
+		setCondition(registry, v1alpha1.ConditionTypeDegraded, metav1.ConditionFalse, "AsExpected", "")
+	}
+
+	return rolledOut
+}
+
+// deploymentFailure reports whether the Deployment failed to create pods or to make progress.
+func deploymentFailure(deployment *appsv1.Deployment) (string, string, bool) {
+	for _, condition := range deployment.Status.Conditions {
+		switch {
+		case condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == apiv1.ConditionTrue:
+			return "ReplicaFailure", condition.Message, true
+		case condition.Type == appsv1.DeploymentProgressing && condition.Status == apiv1.ConditionFalse:
+			return "ProgressDeadlineExceeded", condition.Message, true
+		}
+	}
+	return "", "", false
+}
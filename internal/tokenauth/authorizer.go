@@ -0,0 +1,150 @@
+package tokenauth
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+)
+
+// Authorizer decides which of the requested actions a user is granted on a registry.
+type Authorizer interface {
+	// Authorize returns the subset of the requested access granted to the user.
+	Authorize(
+		ctx context.Context,
+		user authenticationv1.UserInfo,
+		registry *registryoperatordevv1alpha1.Registry,
+		requested []*ResourceActions,
+	) ([]*ResourceActions, error)
+}
+
+// PolicyAuthorizer grants the access of the RegistryAccessPolicies referencing the registry.
+// Users without a matching policy are granted nothing.
+type PolicyAuthorizer struct {
+	Client client.Client
+}
+
+func (a *PolicyAuthorizer) Authorize(
+	ctx context.Context,
+	user authenticationv1.UserInfo,
+	registry *registryoperatordevv1alpha1.Registry,
+	requested []*ResourceActions,
+) ([]*ResourceActions, error) {
+	policies := &registryoperatordevv1alpha1.RegistryAccessPolicyList{}
+	err := a.Client.List(ctx, policies, client.InNamespace(registry.Namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []registryoperatordevv1alpha1.AccessRule
+	for _, policy := range policies.Items {
+		if policy.Spec.RegistryRef.Name != registry.Name {
+			continue
+		}
+		for _, rule := range policy.Spec.Rules {
+			if slices.ContainsFunc(rule.Subjects, func(subject registryoperatordevv1alpha1.Subject) bool {
+				return subjectMatches(subject, policy.Namespace, user)
+			}) {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	var granted []*ResourceActions
+	for _, resource := range requested {
+		if resource.Type != "repository" {
+			continue
+		}
+		allowed := allowedActions(rules, resource.Name)
+		if registry.Spec.Proxy != nil {
+			// Pull-through caches are read-only, whatever the policies grant.
+			allowed = slices.DeleteFunc(allowed, func(action registryoperatordevv1alpha1.RegistryAction) bool {
+				return action != registryoperatordevv1alpha1.RegistryActionPull
+			})
+		}
+		var actions []string
+		for _, action := range resource.Actions {
+			if action == "*" {
+				// The wildcard is granted only to users allowed every action.
+				if len(allowed) == 3 {
+					actions = append(actions, action)
+				}
+				continue
+			}
+			if slices.Contains(allowed, registryoperatordevv1alpha1.RegistryAction(action)) {
+				actions = append(actions, action)
+			}
+		}
+		if len(actions) > 0 {
+			granted = append(granted, &ResourceActions{
+				Type:    resource.Type,
+				Name:    resource.Name,
+				Actions: actions,
+			})
+		}
+	}
+	return granted, nil
+}
+
+// subjectMatches reports whether the subject of a policy in the given namespace identifies the user.
+func subjectMatches(subject registryoperatordevv1alpha1.Subject, namespace string, user authenticationv1.UserInfo) bool {
+	switch subject.Kind {
+	case registryoperatordevv1alpha1.SubjectKindUser:
+		return user.Username == subject.Name
+	case registryoperatordevv1alpha1.SubjectKindGroup:
+		return slices.Contains(user.Groups, subject.Name)
+	case registryoperatordevv1alpha1.SubjectKindServiceAccount:
+		if subject.Namespace != "" {
+			namespace = subject.Namespace
+		}
+		return user.Username == "system:serviceaccount:"+namespace+":"+subject.Name
+	default:
+		return false
+	}
+}
+
+// allowedActions returns the distinct actions the rules allow on the repository.
+func allowedActions(rules []registryoperatordevv1alpha1.AccessRule, repository string) []registryoperatordevv1alpha1.RegistryAction {
+	var allowed []registryoperatordevv1alpha1.RegistryAction
+	for _, rule := range rules {
+		if !slices.ContainsFunc(rule.Repositories, func(pattern string) bool {
+			return repositoryMatches(pattern, repository)
+		}) {
+			continue
+		}
+		for _, action := range rule.Actions {
+			if !slices.Contains(allowed, action) {
+				allowed = append(allowed, action)
+			}
+		}
+	}
+	return allowed
+}
+
+// repositoryMatches reports whether the repository name matches the glob pattern.
+// "*" matches any characters but "/", "**" matches any characters and "?" matches a single character but "/".
+func repositoryMatches(pattern, repository string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	matched, err := regexp.MatchString(expr.String(), repository)
+	return err == nil && matched
+}
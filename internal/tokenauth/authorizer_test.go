@@ -0,0 +1,318 @@
+package tokenauth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+)
+
+func TestRepositoryMatches(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		repository string
+		want       bool
+	}{
+		{pattern: "app", repository: "app", want: true},
+		{pattern: "app", repository: "app2", want: false},
+		{pattern: "*", repository: "app", want: true},
+		{pattern: "*", repository: "team/app", want: false},
+		{pattern: "team/*", repository: "team/app", want: true},
+		{pattern: "team/*", repository: "team/app/base", want: false},
+		{pattern: "team/*", repository: "other/app", want: false},
+		{pattern: "team/**", repository: "team/app", want: true},
+		{pattern: "team/**", repository: "team/app/base", want: true},
+		{pattern: "team/**", repository: "team", want: false},
+		{pattern: "**", repository: "team/app/base", want: true},
+		{pattern: "**/base", repository: "team/app/base", want: true},
+		{pattern: "**/base", repository: "base", want: false},
+		{pattern: "team/*/base", repository: "team/app/base", want: true},
+		{pattern: "team/*/base", repository: "team/a/b/base", want: false},
+		{pattern: "app-?", repository: "app-1", want: true},
+		{pattern: "app-?", repository: "app-10", want: false},
+		{pattern: "team?app", repository: "team/app", want: false},
+		// Regular expression characters are matched literally.
+		{pattern: "app.v1", repository: "app.v1", want: true},
+		{pattern: "app.v1", repository: "appxv1", want: false},
+		{pattern: "app+", repository: "appp", want: false},
+		{pattern: "(app)", repository: "(app)", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := repositoryMatches(tt.pattern, tt.repository); got != tt.want {
+			t.Errorf("repositoryMatches(%q, %q) = %t, want %t", tt.pattern, tt.repository, got, tt.want)
+		}
+	}
+}
+
+func TestSubjectMatches(t *testing.T) {
+	user := authenticationv1.UserInfo{
+		Username: "jane",
+		Groups:   []string{"developers", "system:authenticated"},
+	}
+	serviceAccount := authenticationv1.UserInfo{
+		Username: "system:serviceaccount:ci:builder",
+		Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:ci"},
+	}
+
+	tests := []struct {
+		name      string
+		subject   registryoperatordevv1alpha1.Subject
+		namespace string
+		user      authenticationv1.UserInfo
+		want      bool
+	}{
+		{
+			name:    "user",
+			subject: registryoperatordevv1alpha1.Subject{Kind: registryoperatordevv1alpha1.SubjectKindUser, Name: "jane"},
+			user:    user,
+			want:    true,
+		},
+		{
+			name:    "other user",
+			subject: registryoperatordevv1alpha1.Subject{Kind: registryoperatordevv1alpha1.SubjectKindUser, Name: "john"},
+			user:    user,
+			want:    false,
+		},
+		{
+			name:    "user named after a group",
+			subject: registryoperatordevv1alpha1.Subject{Kind: registryoperatordevv1alpha1.SubjectKindUser, Name: "developers"},
+			user:    user,
+			want:    false,
+		},
+		{
+			name:    "group",
+			subject: registryoperatordevv1alpha1.Subject{Kind: registryoperatordevv1alpha1.SubjectKindGroup, Name: "developers"},
+			user:    user,
+			want:    true,
+		},
+		{
+			name:    "other group",
+			subject: registryoperatordevv1alpha1.Subject{Kind: registryoperatordevv1alpha1.SubjectKindGroup, Name: "admins"},
+			user:    user,
+			want:    false,
+		},
+		{
+			name:    "group named after the user",
+			subject: registryoperatordevv1alpha1.Subject{Kind: registryoperatordevv1alpha1.SubjectKindGroup, Name: "jane"},
+			user:    user,
+			want:    false,
+		},
+		{
+			name:      "ServiceAccount in the namespace of the policy",
+			subject:   registryoperatordevv1alpha1.Subject{Kind: registryoperatordevv1alpha1.SubjectKindServiceAccount, Name: "builder"},
+			namespace: "ci",
+			user:      serviceAccount,
+			want:      true,
+		},
+		{
+			name:      "ServiceAccount of the same name in another namespace",
+			subject:   registryoperatordevv1alpha1.Subject{Kind: registryoperatordevv1alpha1.SubjectKindServiceAccount, Name: "builder"},
+			namespace: "default",
+			user:      serviceAccount,
+			want:      false,
+		},
+		{
+			name: "ServiceAccount in the namespace of the subject",
+			subject: registryoperatordevv1alpha1.Subject{
+				Kind:      registryoperatordevv1alpha1.SubjectKindServiceAccount,
+				Name:      "builder",
+				Namespace: "ci",
+			},
+			namespace: "default",
+			user:      serviceAccount,
+			want:      true,
+		},
+		{
+			name: "ServiceAccount in another namespace than the subject",
+			subject: registryoperatordevv1alpha1.Subject{
+				Kind:      registryoperatordevv1alpha1.SubjectKindServiceAccount,
+				Name:      "builder",
+				Namespace: "default",
+			},
+			namespace: "ci",
+			user:      serviceAccount,
+			want:      false,
+		},
+		{
+			name:      "user named like a ServiceAccount",
+			subject:   registryoperatordevv1alpha1.Subject{Kind: registryoperatordevv1alpha1.SubjectKindUser, Name: "builder"},
+			namespace: "ci",
+			user:      serviceAccount,
+			want:      false,
+		},
+		{
+			name:    "unknown kind",
+			subject: registryoperatordevv1alpha1.Subject{Kind: "Robot", Name: "jane"},
+			user:    user,
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subjectMatches(tt.subject, tt.namespace, tt.user); got != tt.want {
+				t.Errorf("subjectMatches() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyAuthorizer(t *testing.T) {
+	developer := registryoperatordevv1alpha1.Subject{Kind: registryoperatordevv1alpha1.SubjectKindGroup, Name: "developers"}
+	policy := func(namespace, registry string, rules ...registryoperatordevv1alpha1.AccessRule) client.Object {
+		return &registryoperatordevv1alpha1.RegistryAccessPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace + "-" + registry, Namespace: namespace},
+			Spec: registryoperatordevv1alpha1.RegistryAccessPolicySpec{
+				RegistryRef: corev1.LocalObjectReference{Name: registry},
+				Rules:       rules,
+			},
+		}
+	}
+	rule := func(repositories []string, actions ...registryoperatordevv1alpha1.RegistryAction) registryoperatordevv1alpha1.AccessRule {
+		return registryoperatordevv1alpha1.AccessRule{
+			Subjects:     []registryoperatordevv1alpha1.Subject{developer},
+			Repositories: repositories,
+			Actions:      actions,
+		}
+	}
+	const (
+		pull = registryoperatordevv1alpha1.RegistryActionPull
+		push = registryoperatordevv1alpha1.RegistryActionPush
+		del  = registryoperatordevv1alpha1.RegistryActionDelete
+	)
+	repository := func(name string, actions ...string) *ResourceActions {
+		return &ResourceActions{Type: "repository", Name: name, Actions: actions}
+	}
+
+	tests := []struct {
+		name      string
+		policies  []client.Object
+		proxy     bool
+		requested []*ResourceActions
+		want      []*ResourceActions
+	}{
+		{
+			name:      "no policy",
+			requested: []*ResourceActions{repository("team/app", "pull")},
+		},
+		{
+			name:      "granted actions",
+			policies:  []client.Object{policy("default", "registry", rule([]string{"team/**"}, pull, push))},
+			requested: []*ResourceActions{repository("team/app", "pull", "push", "delete")},
+			want:      []*ResourceActions{repository("team/app", "pull", "push")},
+		},
+		{
+			name: "actions of several rules and policies",
+			policies: []client.Object{
+				policy("default", "registry", rule([]string{"team/*"}, pull)),
+				policy("default", "registry-2", rule([]string{"**"}, push)),
+				&registryoperatordevv1alpha1.RegistryAccessPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "deleters", Namespace: "default"},
+					Spec: registryoperatordevv1alpha1.RegistryAccessPolicySpec{
+						RegistryRef: corev1.LocalObjectReference{Name: "registry"},
+						Rules:       []registryoperatordevv1alpha1.AccessRule{rule([]string{"team/app"}, del)},
+					},
+				},
+			},
+			requested: []*ResourceActions{repository("team/app", "pull", "push", "delete")},
+			want:      []*ResourceActions{repository("team/app", "pull", "delete")},
+		},
+		{
+			name:      "repository not matched",
+			policies:  []client.Object{policy("default", "registry", rule([]string{"team/*"}, pull))},
+			requested: []*ResourceActions{repository("team/app/base", "pull"), repository("other/app", "pull")},
+		},
+		{
+			name: "rule of another subject",
+			policies: []client.Object{policy("default", "registry", registryoperatordevv1alpha1.AccessRule{
+				Subjects:     []registryoperatordevv1alpha1.Subject{{Kind: registryoperatordevv1alpha1.SubjectKindGroup, Name: "admins"}},
+				Repositories: []string{"**"},
+				Actions:      []registryoperatordevv1alpha1.RegistryAction{pull, push, del},
+			})},
+			requested: []*ResourceActions{repository("team/app", "pull")},
+		},
+		{
+			name:      "policy in another namespace",
+			policies:  []client.Object{policy("other", "registry", rule([]string{"**"}, pull, push, del))},
+			requested: []*ResourceActions{repository("team/app", "pull", "push", "delete", "*")},
+		},
+		{
+			name:      "policy for another registry",
+			policies:  []client.Object{policy("default", "other", rule([]string{"**"}, pull, push, del))},
+			requested: []*ResourceActions{repository("team/app", "pull", "push", "delete", "*")},
+		},
+		{
+			name:      "wildcard with every action allowed",
+			policies:  []client.Object{policy("default", "registry", rule([]string{"**"}, pull, push, del))},
+			requested: []*ResourceActions{repository("team/app", "*")},
+			want:      []*ResourceActions{repository("team/app", "*")},
+		},
+		{
+			name:      "wildcard without delete",
+			policies:  []client.Object{policy("default", "registry", rule([]string{"**"}, pull, push))},
+			requested: []*ResourceActions{repository("team/app", "*", "pull")},
+			want:      []*ResourceActions{repository("team/app", "pull")},
+		},
+		{
+			name: "wildcard with the actions allowed by several rules",
+			policies: []client.Object{policy("default", "registry",
+				rule([]string{"team/**"}, pull, push),
+				rule([]string{"team/app"}, del),
+			)},
+			requested: []*ResourceActions{repository("team/app", "*"), repository("team/base", "*")},
+			want:      []*ResourceActions{repository("team/app", "*")},
+		},
+		{
+			name:      "proxy is pull-only",
+			policies:  []client.Object{policy("default", "registry", rule([]string{"**"}, pull, push, del))},
+			proxy:     true,
+			requested: []*ResourceActions{repository("library/nginx", "pull", "push", "delete")},
+			want:      []*ResourceActions{repository("library/nginx", "pull")},
+		},
+		{
+			name:      "proxy never grants the wildcard",
+			policies:  []client.Object{policy("default", "registry", rule([]string{"**"}, pull, push, del))},
+			proxy:     true,
+			requested: []*ResourceActions{repository("library/nginx", "*")},
+		},
+		{
+			name:      "resources other than repositories",
+			policies:  []client.Object{policy("default", "registry", rule([]string{"**"}, pull, push, del))},
+			requested: []*ResourceActions{{Type: "registry", Name: "catalog", Actions: []string{"*"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := registryoperatordevv1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.policies...).Build()
+			registry := &registryoperatordevv1alpha1.Registry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"},
+			}
+			if tt.proxy {
+				registry.Spec.Proxy = &registryoperatordevv1alpha1.RegistryProxy{RemoteURL: "https://registry-1.docker.io"}
+			}
+			user := authenticationv1.UserInfo{Username: "jane", Groups: []string{"developers"}}
+
+			got, err := (&PolicyAuthorizer{Client: c}).Authorize(context.Background(), user, registry, tt.requested)
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authorize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
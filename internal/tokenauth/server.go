@@ -0,0 +1,257 @@
+package tokenauth
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+)
+
+// errUnauthenticated is returned for requests without valid Kubernetes credentials.
+var errUnauthenticated = errors.New("authentication required")
+
+// Server serves the token endpoint of the distribution token authentication protocol over TLS.
+// Clients authenticate with Kubernetes tokens, passed either as the password of basic authentication
+// or as a bearer token, and are granted the requested scopes the Authorizer allows.
+type Server struct {
+	// Addr is the address the server listens on.
+	Addr string
+	// CertDir is the directory holding the tls.crt and tls.key files the server is served with.
+	// The files are reloaded when they change.
+	CertDir string
+	// TLSOpts customize the TLS configuration of the server.
+	TLSOpts []func(*tls.Config)
+	// Audiences are the audiences the Kubernetes tokens of clients must be issued for.
+	// Tokens issued for other audiences, e.g. the API server, are rejected.
+	Audiences []string
+	// Client reads registries and reviews the tokens of clients.
+	Client     client.Client
+	Issuer     *TokenIssuer
+	Authorizer Authorizer
+}
+
+// NeedLeaderElection allows every replica of the operator to issue tokens.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves the token endpoint until the context is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	l := log.FromContext(ctx).WithName("token-auth")
+	watcher, err := certwatcher.New(
+		filepath.Join(s.CertDir, "tls.crt"),
+		filepath.Join(s.CertDir, "tls.key"),
+	)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := watcher.Start(ctx); err != nil {
+			l.Error(err, "Failed to watch the serving certificate")
+		}
+	}()
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: watcher.GetCertificate,
+	}
+	for _, opt := range s.TLSOpts {
+		opt(tlsConfig)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/token", s)
+	server := &http.Server{
+		Addr:              s.Addr,
+		Handler:           mux,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(_ net.Listener) context.Context {
+			return log.IntoContext(context.Background(), l)
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			l.Error(err, "Failed to shut down the token server")
+		}
+	}()
+
+	l.Info("Starting token server", "addr", s.Addr)
+	if err := server.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP issues a token for the registry named by the service parameter.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	l := log.FromContext(ctx)
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "only GET is supported")
+		return
+	}
+
+	query := r.URL.Query()
+	service := query.Get("service")
+	registry, err := s.getRegistry(ctx, service)
+	if err != nil {
+		l.Info("Rejected token request for unknown service", "service", service, "error", err.Error())
+		writeError(w, http.StatusBadRequest, "DENIED", "unknown service")
+		return
+	}
+
+	user, err := s.authenticate(ctx, r)
+	if err != nil {
+		if errors.Is(err, errUnauthenticated) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+Issuer+`"`)
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", err.Error())
+			return
+		}
+		l.Error(err, "Failed to review the client token", "service", service)
+		writeError(w, http.StatusInternalServerError, "UNKNOWN", "failed to review the client token")
+		return
+	}
+
+	granted, err := s.Authorizer.Authorize(ctx, *user, registry, parseScopes(query["scope"]))
+	if err != nil {
+		l.Error(err, "Failed to authorize the client", "service", service, "user", user.Username)
+		writeError(w, http.StatusInternalServerError, "UNKNOWN", "failed to authorize the client")
+		return
+	}
+
+	now := time.Now()
+	token, err := s.Issuer.Issue(user.Username, service, granted, now)
+	if err != nil {
+		l.Error(err, "Failed to issue a token", "service", service, "user", user.Username)
+		writeError(w, http.StatusInternalServerError, "UNKNOWN", "failed to issue a token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"token":        token,
+		"access_token": token,
+		"expires_in":   int(s.Issuer.Expiration.Seconds()),
+		"issued_at":    now.UTC().Format(time.RFC3339),
+	})
+}
+
+// getRegistry returns the registry with token authentication identified by a "namespace/name" service.
+func (s *Server) getRegistry(ctx context.Context, service string) (*registryoperatordevv1alpha1.Registry, error) {
+	namespace, name, found := strings.Cut(service, "/")
+	if !found || namespace == "" || name == "" {
+		return nil, errors.New("service is not in the namespace/name format")
+	}
+	registry := &registryoperatordevv1alpha1.Registry{}
+	err := s.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, registry)
+	if err != nil {
+		return nil, err
+	}
+	if registry.Spec.Auth == nil || registry.Spec.Auth.Token == nil {
+		return nil, errors.New("registry does not use token authentication")
+	}
+	return registry, nil
+}
+
+// authenticate reviews the Kubernetes token the client sent and returns the user it belongs to.
+// The token must be valid for one of the audiences of the server.
+func (s *Server) authenticate(ctx context.Context, r *http.Request) (*authenticationv1.UserInfo, error) {
+	token := ""
+	if _, password, ok := r.BasicAuth(); ok {
+		token = password
+	} else if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+	if token == "" {
+		return nil, errUnauthenticated
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: s.Audiences,
+		},
+	}
+	err := s.Client.Create(ctx, review)
+	if err != nil {
+		if apierrors.IsBadRequest(err) {
+			return nil, errUnauthenticated
+		}
+		return nil, err
+	}
+	if !review.Status.Authenticated {
+		return nil, errUnauthenticated
+	}
+	// Authenticators unaware of audiences may accept the token regardless,
+	// they are told apart by the audiences missing from the status.
+	if !slices.ContainsFunc(review.Status.Audiences, func(audience string) bool {
+		return slices.Contains(s.Audiences, audience)
+	}) {
+		return nil, errUnauthenticated
+	}
+	return &review.Status.User, nil
+}
+
+// parseScopes parses the requested scopes, e.g. "repository:team/app:pull,push".
+// A scope parameter may hold several space separated scopes. The name may contain colons, as in
+// "repository:localhost:5000/app:pull". Malformed scopes, missing the type, the name or the actions, are ignored.
+func parseScopes(values []string) []*ResourceActions {
+	var scopes []*ResourceActions
+	for _, value := range values {
+		for _, scope := range strings.Fields(value) {
+			first := strings.Index(scope, ":")
+			last := strings.LastIndex(scope, ":")
+			if first == last {
+				continue
+			}
+			resource := &ResourceActions{
+				Type: scope[:first],
+				Name: scope[first+1 : last],
+			}
+			for _, action := range strings.Split(scope[last+1:], ",") {
+				if action != "" && !slices.Contains(resource.Actions, action) {
+					resource.Actions = append(resource.Actions, action)
+				}
+			}
+			if resource.Type == "" || resource.Name == "" || len(resource.Actions) == 0 {
+				continue
+			}
+			scopes = append(scopes, resource)
+		}
+	}
+	return scopes
+}
+
+// writeError writes an error in the format of the distribution API.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]string{
+			{
+				"code":    code,
+				"message": message,
+			},
+		},
+	})
+}
@@ -0,0 +1,355 @@
+package tokenauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+)
+
+const (
+	testAudience = "registry-operator"
+	testToken    = "kubernetes-token"
+	testUser     = "system:serviceaccount:ci:builder"
+)
+
+// authorizerFunc adapts a function to the Authorizer interface.
+type authorizerFunc func(
+	ctx context.Context,
+	user authenticationv1.UserInfo,
+	registry *registryoperatordevv1alpha1.Registry,
+	requested []*ResourceActions,
+) ([]*ResourceActions, error)
+
+func (f authorizerFunc) Authorize(
+	ctx context.Context,
+	user authenticationv1.UserInfo,
+	registry *registryoperatordevv1alpha1.Registry,
+	requested []*ResourceActions,
+) ([]*ResourceActions, error) {
+	return f(ctx, user, registry, requested)
+}
+
+// grantRequested grants every requested action.
+func grantRequested(
+	_ context.Context,
+	_ authenticationv1.UserInfo,
+	_ *registryoperatordevv1alpha1.Registry,
+	requested []*ResourceActions,
+) ([]*ResourceActions, error) {
+	return requested, nil
+}
+
+// acceptToken authenticates testToken for testAudience as testUser, as the API server does.
+func acceptToken(review *authenticationv1.TokenReview) error {
+	if review.Spec.Token != testToken || !reflect.DeepEqual(review.Spec.Audiences, []string{testAudience}) {
+		return nil
+	}
+	review.Status = authenticationv1.TokenReviewStatus{
+		Authenticated: true,
+		User:          authenticationv1.UserInfo{Username: testUser},
+		Audiences:     review.Spec.Audiences,
+	}
+	return nil
+}
+
+// newTestServer returns a token server whose TokenReviews are answered by review.
+func newTestServer(t *testing.T, review func(*authenticationv1.TokenReview) error, authorizer Authorizer) *Server {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		registryoperatordevv1alpha1.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registries := []client.Object{
+		&registryoperatordevv1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"},
+			Spec: registryoperatordevv1alpha1.RegistrySpec{
+				Auth: &registryoperatordevv1alpha1.RegistryAuth{Token: &registryoperatordevv1alpha1.TokenAuth{}},
+			},
+		},
+		&registryoperatordevv1alpha1.Registry{
+			ObjectMeta: metav1.ObjectMeta{Name: "htpasswd", Namespace: "default"},
+			Spec: registryoperatordevv1alpha1.RegistrySpec{
+				Auth: &registryoperatordevv1alpha1.RegistryAuth{Htpasswd: &registryoperatordevv1alpha1.HtpasswdAuth{}},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(registries...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if tokenReview, ok := obj.(*authenticationv1.TokenReview); ok {
+					return review(tokenReview)
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).
+		Build()
+	return &Server{
+		Audiences:  []string{testAudience},
+		Client:     c,
+		Issuer:     &TokenIssuer{Key: newTestSigningKey(t), Expiration: 5 * time.Minute},
+		Authorizer: authorizer,
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	const target = "/token?service=default/registry&scope=repository:team/app:pull,push"
+	failAuthorization := authorizerFunc(func(
+		context.Context,
+		authenticationv1.UserInfo,
+		*registryoperatordevv1alpha1.Registry,
+		[]*ResourceActions,
+	) ([]*ResourceActions, error) {
+		return nil, errors.New("policies are not readable")
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		auth       func(r *http.Request)
+		review     func(*authenticationv1.TokenReview) error
+		authorizer Authorizer
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "method other than GET",
+			method:     http.MethodPost,
+			target:     target,
+			wantStatus: http.StatusMethodNotAllowed,
+			wantCode:   "UNSUPPORTED",
+		},
+		{
+			name:       "service missing",
+			target:     "/token?scope=repository:team/app:pull",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "DENIED",
+		},
+		{
+			name:       "service not in the namespace/name format",
+			target:     "/token?service=registry",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "DENIED",
+		},
+		{
+			name:       "unknown registry",
+			target:     "/token?service=default/missing",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "DENIED",
+		},
+		{
+			name:       "registry without token authentication",
+			target:     "/token?service=default/htpasswd",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "DENIED",
+		},
+		{
+			name:       "no credentials",
+			target:     target,
+			auth:       func(*http.Request) {},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "UNAUTHORIZED",
+		},
+		{
+			name:       "token not authenticated",
+			target:     target,
+			auth:       func(r *http.Request) { r.SetBasicAuth("builder", "other-token") },
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "UNAUTHORIZED",
+		},
+		{
+			name:   "token review rejected as malformed",
+			target: target,
+			review: func(*authenticationv1.TokenReview) error {
+				return apierrors.NewBadRequest("token is malformed")
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "UNAUTHORIZED",
+		},
+		{
+			name:   "token issued for another audience",
+			target: target,
+			review: func(review *authenticationv1.TokenReview) error {
+				// Authenticators unaware of audiences accept the token without echoing the audiences.
+				review.Status = authenticationv1.TokenReviewStatus{
+					Authenticated: true,
+					User:          authenticationv1.UserInfo{Username: testUser},
+					Audiences:     []string{"https://kubernetes.default.svc"},
+				}
+				return nil
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "UNAUTHORIZED",
+		},
+		{
+			name:   "token review failed",
+			target: target,
+			review: func(*authenticationv1.TokenReview) error {
+				return apierrors.NewServiceUnavailable("API server is unavailable")
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "UNKNOWN",
+		},
+		{
+			name:       "authorization failed",
+			target:     target,
+			authorizer: failAuthorization,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "UNKNOWN",
+		},
+		{
+			name:       "basic authentication",
+			target:     target,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "bearer token",
+			target:     target,
+			auth:       func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+testToken) },
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.method == "" {
+				tt.method = http.MethodGet
+			}
+			if tt.auth == nil {
+				tt.auth = func(r *http.Request) { r.SetBasicAuth("builder", testToken) }
+			}
+			if tt.review == nil {
+				tt.review = acceptToken
+			}
+			if tt.authorizer == nil {
+				tt.authorizer = authorizerFunc(grantRequested)
+			}
+			server := newTestServer(t, tt.review, tt.authorizer)
+
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			tt.auth(r)
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("WWW-Authenticate header is missing")
+			}
+			if tt.wantCode != "" {
+				var body struct {
+					Errors []struct {
+						Code string `json:"code"`
+					} `json:"errors"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				if len(body.Errors) != 1 || body.Errors[0].Code != tt.wantCode {
+					t.Errorf("errors = %+v, want the %s code", body.Errors, tt.wantCode)
+				}
+				return
+			}
+
+			var body struct {
+				Token       string `json:"token"`
+				AccessToken string `json:"access_token"`
+				ExpiresIn   int    `json:"expires_in"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Token == "" || body.AccessToken != body.Token {
+				t.Errorf("token = %q, access_token = %q, want the same token", body.Token, body.AccessToken)
+			}
+			if body.ExpiresIn != 300 {
+				t.Errorf("expires_in = %d, want 300", body.ExpiresIn)
+			}
+			_, claims := decodeToken(t, body.Token, server.Issuer.Key.Certificate)
+			if claims.Subject != testUser || claims.Audience != "default/registry" {
+				t.Errorf("sub = %q, aud = %q, want %q and default/registry", claims.Subject, claims.Audience, testUser)
+			}
+			want := []*ResourceActions{{Type: "repository", Name: "team/app", Actions: []string{"pull", "push"}}}
+			if !reflect.DeepEqual(claims.Access, want) {
+				t.Errorf("access = %+v, want %+v", claims.Access, want)
+			}
+		})
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []*ResourceActions
+	}{
+		{
+			name:   "repository",
+			values: []string{"repository:team/app:pull,push"},
+			want:   []*ResourceActions{{Type: "repository", Name: "team/app", Actions: []string{"pull", "push"}}},
+		},
+		{
+			name:   "space separated scopes and repeated parameters",
+			values: []string{"repository:a:pull repository:b:push", "registry:catalog:*"},
+			want: []*ResourceActions{
+				{Type: "repository", Name: "a", Actions: []string{"pull"}},
+				{Type: "repository", Name: "b", Actions: []string{"push"}},
+				{Type: "registry", Name: "catalog", Actions: []string{"*"}},
+			},
+		},
+		{
+			name:   "name with a port",
+			values: []string{"repository:localhost:5000/app:pull"},
+			want:   []*ResourceActions{{Type: "repository", Name: "localhost:5000/app", Actions: []string{"pull"}}},
+		},
+		{
+			name:   "empty and repeated actions",
+			values: []string{"repository:app:pull,,pull,"},
+			want:   []*ResourceActions{{Type: "repository", Name: "app", Actions: []string{"pull"}}},
+		},
+		{
+			name: "malformed scopes",
+			values: []string{
+				"",
+				"repository",
+				"repository:app",
+				"repository::pull",
+				":app:pull",
+				"repository:app:",
+				"repository:app:,",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseScopes(tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseScopes(%q) = %+v, want %+v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package tokenauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base32"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// signingKeyValidity is how long the self-signed certificate of a generated signing key is valid.
+const signingKeyValidity = 10 * 365 * 24 * time.Hour
+
+// SigningKey is the key tokens are signed with, together with the certificate registries verify them with.
+type SigningKey struct {
+	PrivateKey *rsa.PrivateKey
+	// Certificate is the PEM encoded self-signed certificate of the key, used as the registry root cert bundle.
+	Certificate []byte
+	// KeyID identifies the key in the token header, in the libtrust format the registry looks keys up by.
+	KeyID string
+}
+
+// LoadOrCreateSigningKey reads the signing key from a kubernetes.io/tls Secret,
+// generating the key and the Secret on first use.
+func LoadOrCreateSigningKey(ctx context.Context, reader client.Reader, writer client.Writer, name types.NamespacedName) (*SigningKey, error) {
+	secret := &apiv1.Secret{}
+	err := reader.Get(ctx, name, secret)
+	if err == nil {
+		return parseSigningKey(secret.Data[apiv1.TLSCertKey], secret.Data[apiv1.TLSPrivateKeyKey])
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	certificate, privateKey, err := generateSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	secret = &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name.Name,
+			Namespace: name.Namespace,
+		},
+		Type: apiv1.SecretTypeTLS,
+		Data: map[string][]byte{
+			apiv1.TLSCertKey:       certificate,
+			apiv1.TLSPrivateKeyKey: privateKey,
+		},
+	}
+	err = writer.Create(ctx, secret)
+	if apierrors.IsAlreadyExists(err) {
+		// Another replica of the operator generated the key first.
+		return LoadOrCreateSigningKey(ctx, reader, writer, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseSigningKey(certificate, privateKey)
+}
+
+// generateSigningKey generates an RSA key and a self-signed certificate for it, both PEM encoded.
+func generateSigningKey() ([]byte, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: Issuer},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(signingKeyValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certificate, privateKey, nil
+}
+
+// parseSigningKey parses a PEM encoded certificate and its RSA private key.
+func parseSigningKey(certificate, privateKey []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, errors.New("signing key is not PEM encoded")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the signing key: %w", err)
+	}
+	if block, _ = pem.Decode(certificate); block == nil {
+		return nil, errors.New("signing certificate is not PEM encoded")
+	}
+	if _, err = x509.ParseCertificate(block.Bytes); err != nil {
+		return nil, fmt.Errorf("failed to parse the signing certificate: %w", err)
+	}
+	keyID, err := keyID(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{
+		PrivateKey:  key,
+		Certificate: certificate,
+		KeyID:       keyID,
+	}, nil
+}
+
+// keyID returns the libtrust fingerprint of the public key: the base32 encoded first 240 bits
+// of the SHA-256 hash of its DER encoding, split into groups of four characters.
+func keyID(publicKey *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	encoded := base32.StdEncoding.EncodeToString(sum[:30])
+
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, ":"), nil
+}
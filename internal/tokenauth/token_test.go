@@ -0,0 +1,201 @@
+package tokenauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newTestSigningKey generates a signing key the way the operator does on first use.
+func newTestSigningKey(t *testing.T) *SigningKey {
+	t.Helper()
+	certificate, privateKey, err := generateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := parseSigningKey(certificate, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// decodeToken verifies the signature of the token against the certificate of the key
+// and returns its decoded header and claims.
+func decodeToken(t *testing.T, token string, certificate []byte) (header, claims) {
+	t.Helper()
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		t.Fatalf("token has %d segments, want 3", len(segments))
+	}
+
+	block, _ := pem.Decode(certificate)
+	if block == nil {
+		t.Fatal("certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		t.Fatalf("certificate holds a %T, want an RSA public key", cert.PublicKey)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(segments[0] + "." + segments[1]))
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Fatalf("signature does not verify against the certificate: %v", err)
+	}
+
+	var h header
+	var c claims
+	for i, value := range []any{&h, &c} {
+		data, err := base64.RawURLEncoding.DecodeString(segments[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return h, c
+}
+
+// libtrustKeyID derives the key ID the registry looks the certificate up by, as libtrust does
+// from the subject public key info of the certificate.
+func libtrustKeyID(t *testing.T, certificate []byte) string {
+	t.Helper()
+	block, _ := pem.Decode(certificate)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	encoded := strings.TrimRight(base32.StdEncoding.EncodeToString(sum[:30]), "=")
+	var id strings.Builder
+	for i := 0; i < len(encoded); i += 4 {
+		if i > 0 {
+			id.WriteString(":")
+		}
+		id.WriteString(encoded[i : i+4])
+	}
+	return id.String()
+}
+
+func TestIssue(t *testing.T) {
+	key := newTestSigningKey(t)
+	issuer := &TokenIssuer{Key: key, Expiration: 5 * time.Minute}
+	now := time.Unix(1700000000, 0)
+	access := []*ResourceActions{{Type: "repository", Name: "team/app", Actions: []string{"pull", "push"}}}
+
+	token, err := issuer.Issue("system:serviceaccount:ci:builder", "default/registry", access, now)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	h, c := decodeToken(t, token, key.Certificate)
+
+	if h.Type != "JWT" || h.Algorithm != "RS256" {
+		t.Errorf("header = %+v, want a JWT signed with RS256", h)
+	}
+	if want := libtrustKeyID(t, key.Certificate); h.KeyID != want {
+		t.Errorf("kid = %q, want %q derived from the certificate", h.KeyID, want)
+	}
+	if !regexp.MustCompile(`^([A-Z2-7]{4}:){11}[A-Z2-7]{4}$`).MatchString(h.KeyID) {
+		t.Errorf("kid = %q is not in the libtrust format", h.KeyID)
+	}
+
+	if c.Issuer != Issuer {
+		t.Errorf("iss = %q, want %q", c.Issuer, Issuer)
+	}
+	if c.Subject != "system:serviceaccount:ci:builder" {
+		t.Errorf("sub = %q", c.Subject)
+	}
+	if c.Audience != "default/registry" {
+		t.Errorf("aud = %q, want default/registry", c.Audience)
+	}
+	if c.IssuedAt != now.Unix() {
+		t.Errorf("iat = %d, want %d", c.IssuedAt, now.Unix())
+	}
+	if c.NotBefore != now.Add(-time.Minute).Unix() {
+		t.Errorf("nbf = %d, want a minute before the issue time", c.NotBefore)
+	}
+	if c.Expiration != now.Add(5*time.Minute).Unix() {
+		t.Errorf("exp = %d, want %d", c.Expiration, now.Add(5*time.Minute).Unix())
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(c.JWTID) {
+		t.Errorf("jti = %q, want 16 random hex encoded bytes", c.JWTID)
+	}
+	if !reflect.DeepEqual(c.Access, access) {
+		t.Errorf("access = %+v, want %+v", c.Access, access)
+	}
+
+	// Tokens granting nothing carry an empty access claim, which the registry requires.
+	token, err = issuer.Issue("anonymous", "default/registry", nil, now)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if _, c = decodeToken(t, token, key.Certificate); c.Access == nil || len(c.Access) != 0 {
+		t.Errorf("access = %+v, want an empty list", c.Access)
+	}
+}
+
+func TestLoadOrCreateSigningKey(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	name := types.NamespacedName{Namespace: "registry-operator-system", Name: "token-signing-key"}
+
+	created, err := LoadOrCreateSigningKey(ctx, c, c, name)
+	if err != nil {
+		t.Fatalf("LoadOrCreateSigningKey() error = %v", err)
+	}
+	secret := &apiv1.Secret{}
+	if err := c.Get(ctx, name, secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.Type != apiv1.SecretTypeTLS {
+		t.Errorf("Secret type = %q, want %q", secret.Type, apiv1.SecretTypeTLS)
+	}
+
+	loaded, err := LoadOrCreateSigningKey(ctx, c, c, name)
+	if err != nil {
+		t.Fatalf("LoadOrCreateSigningKey() error = %v", err)
+	}
+	if !loaded.PrivateKey.Equal(created.PrivateKey) || loaded.KeyID != created.KeyID {
+		t.Error("the key stored in the Secret was not reused")
+	}
+	if want := libtrustKeyID(t, loaded.Certificate); loaded.KeyID != want {
+		t.Errorf("KeyID = %q, want %q derived from the certificate", loaded.KeyID, want)
+	}
+
+	secret.Data[apiv1.TLSPrivateKeyKey] = []byte("not a key")
+	if err := c.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrCreateSigningKey(ctx, c, c, name); err == nil {
+		t.Error("expected an error for a Secret without a PEM encoded key")
+	}
+}
@@ -0,0 +1,262 @@
+package v1
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/distribution/reference"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=ignore,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod.registry-operator.dev,admissionReviewVersions=v1
+
+// PodImageRewriter rewrites the images of pods in opted-in namespaces to the Registries mirroring their hosts.
+// Only Registries in the namespace of the pod, or allowed by its namespace, are mirrors for it.
+// Failures of the webhook are ignored by the API server, pods are then created with their original images.
+type PodImageRewriter struct {
+	Client         client.Client
+	ServiceFactory *factories.ServiceFactory
+}
+
+// SetupPodWebhookWithManager registers the image rewriting webhook for Pods with the manager.
+func SetupPodWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&corev1.Pod{}).
+		WithDefaulter(&PodImageRewriter{
+			Client:         mgr.GetClient(),
+			ServiceFactory: factories.NewServiceFactory(),
+		}).
+		Complete()
+}
+
+func (r *PodImageRewriter) Default(ctx context.Context, obj runtime.Object) error {
+	l := log.FromContext(ctx)
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return fmt.Errorf("expected a Pod but got a %T", obj)
+	}
+
+	if managedByOperator(pod) {
+		return nil
+	}
+
+	// The namespace of pods created through a controller is only known from the request.
+	namespace := pod.Namespace
+	if request, err := admission.RequestFromContext(ctx); err == nil && request.Namespace != "" {
+		namespace = request.Namespace
+	}
+
+	ns, err := r.optedInNamespace(ctx, namespace)
+	if err != nil || ns == nil {
+		return err
+	}
+	mirrors, err := r.mirrors(ctx, ns)
+	if err != nil || len(mirrors) == 0 {
+		return err
+	}
+
+	var targets []*registryoperatordevv1alpha1.Registry
+	rewrite := func(containers []corev1.Container) {
+		for i := range containers {
+			registry, image, ok := r.rewriteImage(containers[i].Image, mirrors)
+			if !ok {
+				continue
+			}
+			l.Info("Rewriting image to", "registry", registry.Name, "namespace", namespace,
+				"image", containers[i].Image, "rewritten", image)
+			containers[i].Image = image
+			if !slices.Contains(targets, registry) {
+				targets = append(targets, registry)
+			}
+		}
+	}
+	rewrite(pod.Spec.InitContainers)
+	rewrite(pod.Spec.Containers)
+
+	for _, registry := range targets {
+		if err := r.injectPullSecret(ctx, pod, namespace, registry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// managedByOperator reports whether the pod belongs to a registry, its redis or its garbage collection.
+// Their images must not be rewritten: a proxy Registry pulling its own image through itself never starts.
+func managedByOperator(pod *corev1.Pod) bool {
+	if pod.Labels["registry"] == "" {
+		return false
+	}
+	switch pod.Labels["app"] {
+	case "registry", "registry-redis", "registry-garbage-collection":
+		return true
+	}
+	return false
+}
+
+// optedInNamespace returns the namespace if it opted into the rewriting of pod images, nil otherwise.
+func (r *PodImageRewriter) optedInNamespace(ctx context.Context, name string) (*corev1.Namespace, error) {
+	ns := &corev1.Namespace{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: name}, ns); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if ns.Labels[registryoperatordevv1alpha1.ImageRewriteLabel] != "enabled" {
+		return nil, nil
+	}
+	return ns, nil
+}
+
+// mirrors maps the upstream hosts to the Registries mirroring them for the pods in the namespace.
+// When several Registries mirror the same host, the first candidate wins. Registries that are not serving yet are skipped.
+func (r *PodImageRewriter) mirrors(
+	ctx context.Context,
+	namespace *corev1.Namespace,
+) (map[string]*registryoperatordevv1alpha1.Registry, error) {
+	candidates, err := r.mirrorCandidates(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	mirrors := map[string]*registryoperatordevv1alpha1.Registry{}
+	for _, registry := range candidates {
+		phase := registry.Status.Phase
+		if phase != registryoperatordevv1alpha1.RegistryPhaseRunning && phase != registryoperatordevv1alpha1.RegistryPhaseUpdating {
+			continue
+		}
+		host, ok := mirroredHost(registry)
+		if _, taken := mirrors[host]; ok && !taken {
+			mirrors[host] = registry
+		}
+	}
+	return mirrors, nil
+}
+
+// mirrorCandidates returns the Registries pods in the namespace may be rewritten to, in order of precedence:
+// the Registries of the namespace by name, then the ones listed in its MirrorRegistriesAnnotation in order.
+// Registries in other namespaces are never candidates unless the namespace lists them,
+// the owners of a Registry must not be able to redirect the images of other tenants.
+func (r *PodImageRewriter) mirrorCandidates(
+	ctx context.Context,
+	namespace *corev1.Namespace,
+) ([]*registryoperatordevv1alpha1.Registry, error) {
+	registries := &registryoperatordevv1alpha1.RegistryList{}
+	if err := r.Client.List(ctx, registries, client.InNamespace(namespace.Name)); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(registries.Items, func(a, b registryoperatordevv1alpha1.Registry) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	candidates := make([]*registryoperatordevv1alpha1.Registry, 0, len(registries.Items))
+	for i := range registries.Items {
+		candidates = append(candidates, &registries.Items[i])
+	}
+
+	for _, allowed := range strings.Split(namespace.Annotations[registryoperatordevv1alpha1.MirrorRegistriesAnnotation], ",") {
+		registryNamespace, name, ok := strings.Cut(strings.TrimSpace(allowed), "/")
+		if !ok || registryNamespace == "" || name == "" || registryNamespace == namespace.Name {
+			continue
+		}
+		registry := &registryoperatordevv1alpha1.Registry{}
+		err := r.Client.Get(ctx, client.ObjectKey{Namespace: registryNamespace, Name: name}, registry)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, registry)
+	}
+	return candidates, nil
+}
+
+// mirroredHost returns the upstream host the Registry mirrors: the host of its spec.proxy.remoteURL,
+// provided it is listed in its MirrorsAnnotation. Registries that are not pull-through caches mirror nothing.
+func mirroredHost(registry *registryoperatordevv1alpha1.Registry) (string, bool) {
+	proxy := registry.Spec.Proxy
+	if proxy == nil {
+		return "", false
+	}
+	remoteURL, err := url.Parse(proxy.RemoteURL)
+	if err != nil || remoteURL.Host == "" {
+		return "", false
+	}
+	remoteHost := normalizeHost(remoteURL.Host)
+	for _, host := range strings.Split(registry.Annotations[registryoperatordevv1alpha1.MirrorsAnnotation], ",") {
+		if normalizeHost(host) == remoteHost {
+			return remoteHost, true
+		}
+	}
+	return "", false
+}
+
+// normalizeHost returns the host as it appears in normalized image references.
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return host
+}
+
+// rewriteImage returns the image moved to the in-cluster address of the Registry mirroring its host.
+// Images on hosts nobody mirrors, and images that cannot be parsed, are not rewritten.
+func (r *PodImageRewriter) rewriteImage(
+	image string,
+	mirrors map[string]*registryoperatordevv1alpha1.Registry,
+) (*registryoperatordevv1alpha1.Registry, string, bool) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, "", false
+	}
+	domain := reference.Domain(named)
+	registry, ok := mirrors[normalizeHost(domain)]
+	if !ok {
+		return nil, "", false
+	}
+	return registry, r.ServiceFactory.Host(registry) + "/" + strings.TrimPrefix(named.String(), domain+"/"), true
+}
+
+// injectPullSecret adds the pull secret distributed for the Registry to the pod,
+// if the Registry asks for it and the secret exists in the namespace of the pod.
+func (r *PodImageRewriter) injectPullSecret(
+	ctx context.Context,
+	pod *corev1.Pod,
+	namespace string,
+	registry *registryoperatordevv1alpha1.Registry,
+) error {
+	l := log.FromContext(ctx)
+	if registry.Annotations[registryoperatordevv1alpha1.InjectPullSecretAnnotation] != "true" {
+		return nil
+	}
+	name := factories.PullSecretName(registry)
+	if slices.ContainsFunc(pod.Spec.ImagePullSecrets, func(ref corev1.LocalObjectReference) bool {
+		return ref.Name == name
+	}) {
+		return nil
+	}
+
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &corev1.Secret{})
+	if apierrors.IsNotFound(err) {
+		l.Info("Pull secret is not distributed to the namespace", "registry", registry.Name, "namespace", namespace)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	pod.Spec.ImagePullSecrets = append(pod.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: name})
+	return nil
+}
@@ -0,0 +1,331 @@
+package v1
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+// newTestRewriter returns a rewriter reading the given objects.
+func newTestRewriter(t *testing.T, objs ...client.Object) *PodImageRewriter {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		registryoperatordevv1alpha1.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &PodImageRewriter{
+		Client:         fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		ServiceFactory: factories.NewServiceFactory(),
+	}
+}
+
+// newMirror returns a running pull-through cache of remoteURL, mirroring the given hosts.
+func newMirror(namespace, name, remoteURL, hosts string) *registryoperatordevv1alpha1.Registry {
+	return &registryoperatordevv1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: map[string]string{registryoperatordevv1alpha1.MirrorsAnnotation: hosts},
+		},
+		Spec: registryoperatordevv1alpha1.RegistrySpec{
+			Proxy: &registryoperatordevv1alpha1.RegistryProxy{RemoteURL: remoteURL},
+		},
+		Status: registryoperatordevv1alpha1.RegistryStatus{Phase: registryoperatordevv1alpha1.RegistryPhaseRunning},
+	}
+}
+
+// newNamespace returns a namespace opted into the rewriting of pod images, allowing the given Registries.
+func newNamespace(name, allowed string) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{registryoperatordevv1alpha1.ImageRewriteLabel: "enabled"},
+	}}
+	if allowed != "" {
+		ns.Annotations = map[string]string{registryoperatordevv1alpha1.MirrorRegistriesAnnotation: allowed}
+	}
+	return ns
+}
+
+func TestRewriteImage(t *testing.T) {
+	dockerHub := newMirror("team", "docker-hub", "https://registry-1.docker.io", "docker.io")
+	quay := newMirror("team", "quay", "https://quay.io", "quay.io")
+	mirrors := map[string]*registryoperatordevv1alpha1.Registry{"docker.io": dockerHub, "quay.io": quay}
+	r := newTestRewriter(t)
+
+	tests := []struct {
+		name         string
+		image        string
+		wantRegistry *registryoperatordevv1alpha1.Registry
+		want         string
+	}{
+		{
+			name:         "official image",
+			image:        "nginx",
+			wantRegistry: dockerHub,
+			want:         "docker-hub.team.svc:5000/library/nginx",
+		},
+		{
+			name:         "official image with a tag",
+			image:        "nginx:1.27",
+			wantRegistry: dockerHub,
+			want:         "docker-hub.team.svc:5000/library/nginx:1.27",
+		},
+		{
+			name:         "user image",
+			image:        "bitnami/redis:7.2",
+			wantRegistry: dockerHub,
+			want:         "docker-hub.team.svc:5000/bitnami/redis:7.2",
+		},
+		{
+			name:         "fully qualified",
+			image:        "docker.io/library/nginx:1.27",
+			wantRegistry: dockerHub,
+			want:         "docker-hub.team.svc:5000/library/nginx:1.27",
+		},
+		{
+			name:         "legacy index host",
+			image:        "index.docker.io/library/nginx:1.27",
+			wantRegistry: dockerHub,
+			want:         "docker-hub.team.svc:5000/library/nginx:1.27",
+		},
+		{
+			name:         "registry host",
+			image:        "registry-1.docker.io/library/nginx:1.27",
+			wantRegistry: dockerHub,
+			want:         "docker-hub.team.svc:5000/library/nginx:1.27",
+		},
+		{
+			name:         "digest",
+			image:        "nginx@" + digest,
+			wantRegistry: dockerHub,
+			want:         "docker-hub.team.svc:5000/library/nginx@" + digest,
+		},
+		{
+			name:         "tag and digest",
+			image:        "quay.io/prometheus/prometheus:v2.53.0@" + digest,
+			wantRegistry: quay,
+			want:         "quay.team.svc:5000/prometheus/prometheus:v2.53.0@" + digest,
+		},
+		{name: "host nobody mirrors", image: "ghcr.io/org/app:1.0"},
+		{name: "host with a port", image: "quay.io:443/prometheus/prometheus"},
+		{name: "empty", image: ""},
+		{name: "uppercase repository", image: "Nginx:1.27"},
+		{name: "empty tag", image: "nginx:"},
+		{name: "malformed digest", image: "nginx@sha256:abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, got, ok := r.rewriteImage(tt.image, mirrors)
+			if ok != (tt.wantRegistry != nil) {
+				t.Fatalf("rewriteImage(%q) rewritten = %t, want %t", tt.image, ok, tt.wantRegistry != nil)
+			}
+			if registry != tt.wantRegistry || got != tt.want {
+				t.Errorf("rewriteImage(%q) = %v, %q, want %v, %q", tt.image, registry, got, tt.wantRegistry, tt.want)
+			}
+		})
+	}
+}
+
+func TestMirrors(t *testing.T) {
+	ctx := context.Background()
+	notServing := newMirror("team", "a-pending", "https://registry-1.docker.io", "docker.io")
+	notServing.Status.Phase = registryoperatordevv1alpha1.RegistryPhasePending
+	notProxy := newMirror("team", "b-not-proxy", "https://registry-1.docker.io", "docker.io")
+	notProxy.Spec.Proxy = nil
+
+	objs := []client.Object{
+		notServing,
+		notProxy,
+		// Claims quay.io while it proxies Docker Hub.
+		newMirror("team", "c-mismatch", "https://registry-1.docker.io", "quay.io"),
+		newMirror("team", "d-docker-hub", "https://registry-1.docker.io", "docker.io"),
+		newMirror("team", "e-docker-hub", "https://registry-1.docker.io", "docker.io"),
+		newMirror("team", "f-upstream", "http://upstream.team.svc:5000", "upstream.team.svc:5000"),
+		newMirror("registry-system", "docker-hub", "https://registry-1.docker.io", "docker.io"),
+		newMirror("registry-system", "quay", "https://quay.io", "quay.io"),
+		newMirror("registry-system", "ghcr", "https://ghcr.io", "ghcr.io"),
+		// Mirrors every host it can for any tenant, without being allowed by them.
+		newMirror("attacker", "gcr", "https://gcr.io", "gcr.io"),
+		newMirror("attacker", "docker-hub", "https://registry-1.docker.io", "docker.io"),
+	}
+	r := newTestRewriter(t, objs...)
+
+	tests := []struct {
+		name      string
+		namespace *corev1.Namespace
+		want      map[string]string
+	}{
+		{
+			name:      "registries of the namespace",
+			namespace: newNamespace("team", ""),
+			want: map[string]string{
+				"docker.io":              "team/d-docker-hub",
+				"upstream.team.svc:5000": "team/f-upstream",
+			},
+		},
+		{
+			name:      "registries of the namespace before the allowed ones",
+			namespace: newNamespace("team", "registry-system/docker-hub, registry-system/quay"),
+			want: map[string]string{
+				"docker.io":              "team/d-docker-hub",
+				"quay.io":                "registry-system/quay",
+				"upstream.team.svc:5000": "team/f-upstream",
+			},
+		},
+		{
+			name:      "allowed registries in the listed order",
+			namespace: newNamespace("other", "attacker/docker-hub,registry-system/docker-hub"),
+			want:      map[string]string{"docker.io": "attacker/docker-hub"},
+		},
+		{
+			name:      "registries of other namespaces are not allowed by default",
+			namespace: newNamespace("other", ""),
+			want:      map[string]string{},
+		},
+		{
+			name:      "malformed and missing entries",
+			namespace: newNamespace("other", "docker-hub,/quay,registry-system/,registry-system/missing,registry-system/ghcr"),
+			want:      map[string]string{"ghcr.io": "registry-system/ghcr"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mirrors, err := r.mirrors(ctx, tt.namespace)
+			if err != nil {
+				t.Fatalf("mirrors() error = %v", err)
+			}
+			got := map[string]string{}
+			for host, registry := range mirrors {
+				got[host] = registry.Namespace + "/" + registry.Name
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("mirrors() = %v, want %v", got, tt.want)
+			}
+			for host, want := range tt.want {
+				if got[host] != want {
+					t.Errorf("mirrors()[%q] = %q, want %q", host, got[host], want)
+				}
+			}
+		})
+	}
+}
+
+func TestInjectPullSecret(t *testing.T) {
+	ctx := context.Background()
+	registry := newMirror("registry-system", "docker-hub", "https://registry-1.docker.io", "docker.io")
+	registry.Annotations[registryoperatordevv1alpha1.InjectPullSecretAnnotation] = "true"
+	noInjection := newMirror("registry-system", "quay", "https://quay.io", "quay.io")
+	name := factories.PullSecretName(registry)
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team"}}
+	other := corev1.LocalObjectReference{Name: "other"}
+
+	tests := []struct {
+		name     string
+		registry *registryoperatordevv1alpha1.Registry
+		existing []corev1.LocalObjectReference
+		objs     []client.Object
+		want     []corev1.LocalObjectReference
+	}{
+		{
+			name:     "injected",
+			registry: registry,
+			existing: []corev1.LocalObjectReference{other},
+			objs:     []client.Object{secret},
+			want:     []corev1.LocalObjectReference{other, {Name: name}},
+		},
+		{
+			name:     "not asked for",
+			registry: noInjection,
+			objs:     []client.Object{&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: factories.PullSecretName(noInjection), Namespace: "team"}}},
+		},
+		{
+			name:     "not distributed to the namespace",
+			registry: registry,
+		},
+		{
+			name:     "already referenced",
+			registry: registry,
+			existing: []corev1.LocalObjectReference{{Name: name}},
+			objs:     []client.Object{secret},
+			want:     []corev1.LocalObjectReference{{Name: name}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRewriter(t, tt.objs...)
+			pod := &corev1.Pod{Spec: corev1.PodSpec{ImagePullSecrets: tt.existing}}
+			if err := r.injectPullSecret(ctx, pod, "team", tt.registry); err != nil {
+				t.Fatalf("injectPullSecret() error = %v", err)
+			}
+			if got := pod.Spec.ImagePullSecrets; !slices.Equal(got, tt.want) {
+				t.Errorf("imagePullSecrets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	ctx := context.Background()
+	registry := newMirror("registry-system", "docker-hub", "https://registry-1.docker.io", "docker.io")
+	r := newTestRewriter(t,
+		registry,
+		newNamespace("team", "registry-system/docker-hub"),
+		newNamespace("other", ""),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "opted-out"}},
+	)
+
+	tests := []struct {
+		name      string
+		namespace string
+		labels    map[string]string
+		want      string
+	}{
+		{name: "allowed", namespace: "team", want: "docker-hub.registry-system.svc:5000/library/nginx:1.27"},
+		{name: "not allowed", namespace: "other", want: "nginx:1.27"},
+		{name: "not opted in", namespace: "opted-out", want: "nginx:1.27"},
+		{name: "unknown namespace", namespace: "missing", want: "nginx:1.27"},
+		{
+			name:      "pod of a registry",
+			namespace: "team",
+			labels:    map[string]string{"app": "registry", "registry": "docker-hub"},
+			want:      "nginx:1.27",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: tt.namespace, Labels: tt.labels},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "init", Image: "nginx:1.27"}},
+					Containers:     []corev1.Container{{Name: "app", Image: "nginx:1.27"}},
+				},
+			}
+			if err := r.Default(ctx, pod); err != nil {
+				t.Fatalf("Default() error = %v", err)
+			}
+			if got := pod.Spec.InitContainers[0].Image; got != tt.want {
+				t.Errorf("init container image = %q, want %q", got, tt.want)
+			}
+			if got := pod.Spec.Containers[0].Image; got != tt.want {
+				t.Errorf("container image = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
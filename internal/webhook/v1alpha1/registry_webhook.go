@@ -0,0 +1,411 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+	"github.com/registry-operator/registry-operator/internal/components"
+	"github.com/registry-operator/registry-operator/internal/components/factories"
+)
+
+// SetupRegistryWebhookWithManager registers the defaulting and validating webhooks for Registries with the manager.
+// The token authentication options are nil when the token issuer of the operator is disabled.
+func SetupRegistryWebhookWithManager(mgr ctrl.Manager, tokenAuth *factories.TokenAuthOptions) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&registryoperatordevv1alpha1.Registry{}).
+		WithDefaulter(&RegistryCustomDefaulter{}).
+		WithValidator(&RegistryCustomValidator{
+			TokenAuth:          tokenAuth,
+			RegistryOperations: components.NewRegistryOperations(mgr.GetClient(), tokenAuth),
+		}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-registry-operator-dev-v1alpha1-registry,mutating=true,failurePolicy=fail,sideEffects=None,groups=registry-operator.dev,resources=registries,verbs=create;update,versions=v1alpha1,name=mregistry.registry-operator.dev,admissionReviewVersions=v1
+
+// RegistryCustomDefaulter applies the defaults of the Registry API, so that the operator never sees a spec
+// without them, whichever way the Registry was created.
+type RegistryCustomDefaulter struct{}
+
+func (d *RegistryCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	registry, ok := obj.(*registryoperatordevv1alpha1.Registry)
+	if !ok {
+		return fmt.Errorf("expected a Registry but got a %T", obj)
+	}
+
+	spec := &registry.Spec
+	if spec.Storage.Type == "" {
+		spec.Storage.Type = registryoperatordevv1alpha1.StorageTypeInMemory
+	}
+	if filesystem := spec.Storage.Filesystem; filesystem != nil {
+		if filesystem.Size.IsZero() {
+			filesystem.Size = resource.MustParse("10Gi")
+		}
+		if len(filesystem.AccessModes) == 0 {
+			filesystem.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+		}
+	}
+	if azure := spec.Storage.Azure; azure != nil && azure.Realm == "" {
+		azure.Realm = "core.windows.net"
+	}
+	if spec.Replicas == nil {
+		replicas := int32(1)
+		spec.Replicas = &replicas
+	}
+	if spec.Service.Type == "" {
+		spec.Service.Type = corev1.ServiceTypeClusterIP
+	}
+	if spec.Service.Port == 0 {
+		spec.Service.Port = 5000
+	}
+	if spec.Auth != nil && spec.Auth.Htpasswd != nil && spec.Auth.Htpasswd.Realm == "" {
+		spec.Auth.Htpasswd.Realm = "Registry Realm"
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-registry-operator-dev-v1alpha1-registry,mutating=false,failurePolicy=fail,sideEffects=None,groups=registry-operator.dev,resources=registries,verbs=create;update,versions=v1alpha1,name=vregistry.registry-operator.dev,admissionReviewVersions=v1
+
+// RegistryCustomValidator rejects Registries the operator cannot turn into a registry,
+// and changes a live registry cannot follow.
+type RegistryCustomValidator struct {
+	// TokenAuth is nil when the token issuer of the operator is disabled.
+	TokenAuth *factories.TokenAuthOptions
+	// RegistryOperations renders the child resources of the validated Registries.
+	RegistryOperations *components.RegistryOperations
+}
+
+func (v *RegistryCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	registry, ok := obj.(*registryoperatordevv1alpha1.Registry)
+	if !ok {
+		return nil, fmt.Errorf("expected a Registry but got a %T", obj)
+	}
+	return v.validate(registry, nil)
+}
+
+func (v *RegistryCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldRegistry, ok := oldObj.(*registryoperatordevv1alpha1.Registry)
+	if !ok {
+		return nil, fmt.Errorf("expected a Registry but got a %T", oldObj)
+	}
+	registry, ok := newObj.(*registryoperatordevv1alpha1.Registry)
+	if !ok {
+		return nil, fmt.Errorf("expected a Registry but got a %T", newObj)
+	}
+	// Registries being deleted only have their finalizer removed.
+	if !registry.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	return v.validate(registry, validateImmutableFields(oldRegistry, registry))
+}
+
+func (v *RegistryCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the spec of the registry on top of the given errors and renders its child resources.
+func (v *RegistryCustomValidator) validate(
+	registry *registryoperatordevv1alpha1.Registry,
+	errs field.ErrorList,
+) (admission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	errs = append(errs, validateStorage(&registry.Spec.Storage, specPath.Child("storage"))...)
+	errs = append(errs, validateReplicas(registry, specPath.Child("replicas"))...)
+	errs = append(errs, validateTLS(registry, specPath.Child("tls"))...)
+	errs = append(errs, validateExposure(registry, specPath.Child("exposure"))...)
+	errs = append(errs, v.validateAuth(registry, specPath.Child("auth"))...)
+	errs = append(errs, validateProxy(registry, specPath.Child("proxy"))...)
+	errs = append(errs, validateDistribution(registry, specPath.Child("distribution"))...)
+	errs = append(errs, validateGarbageCollection(registry, specPath.Child("garbageCollection"))...)
+	errs = append(errs, validateNotifications(registry, specPath.Child("notifications"))...)
+
+	// Anything the checks above missed would only fail once the registry resources are rendered.
+	if len(errs) == 0 {
+		if err := v.RegistryOperations.ValidateRegistryConfig(registry); err != nil {
+			errs = append(errs, field.Forbidden(specPath, err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, apierrors.NewInvalid(registryoperatordevv1alpha1.GroupVersion.WithKind("Registry").GroupKind(), registry.Name, errs)
+	}
+	warnings := annotationWarnings(registry)
+	warnings = append(warnings, cacheWarnings(registry)...)
+	return append(warnings, proxyWarnings(registry)...), nil
+}
+
+// validateStorage checks that only the driver of the storage type is configured
+// and that the maintenance intervals are positive.
+func validateStorage(storage *registryoperatordevv1alpha1.Storage, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	drivers := []struct {
+		storageType registryoperatordevv1alpha1.StorageType
+		set         bool
+	}{
+		{registryoperatordevv1alpha1.StorageTypeFilesystem, storage.Filesystem != nil},
+		{registryoperatordevv1alpha1.StorageTypeS3, storage.S3 != nil},
+		{registryoperatordevv1alpha1.StorageTypeGCS, storage.GCS != nil},
+		{registryoperatordevv1alpha1.StorageTypeAzure, storage.Azure != nil},
+	}
+	for _, driver := range drivers {
+		if driver.set && driver.storageType != storage.Type {
+			errs = append(errs, field.Forbidden(path.Child(string(driver.storageType)),
+				fmt.Sprintf("can only be set when storage type is %s", driver.storageType)))
+		}
+	}
+	if filesystem := storage.Filesystem; filesystem != nil && filesystem.Size.Sign() <= 0 {
+		errs = append(errs, field.Invalid(path.Child("filesystem", "size"), filesystem.Size.String(), "must be greater than zero"))
+	}
+	if maintenance := storage.Maintenance; maintenance != nil && maintenance.UploadPurging != nil {
+		purgingPath := path.Child("maintenance", "uploadPurging")
+		durations := []struct {
+			name     string
+			duration *metav1.Duration
+		}{
+			{"age", maintenance.UploadPurging.Age},
+			{"interval", maintenance.UploadPurging.Interval},
+		}
+		for _, d := range durations {
+			if d.duration != nil && d.duration.Duration <= 0 {
+				errs = append(errs, field.Invalid(purgingPath.Child(d.name), d.duration.Duration.String(), "must be greater than zero"))
+			}
+		}
+	}
+	return errs
+}
+
+// validateTLS checks that the serving certificate comes from exactly one source.
+func validateTLS(registry *registryoperatordevv1alpha1.Registry, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	tls := registry.Spec.TLS
+	if tls == nil {
+		return errs
+	}
+	switch {
+	case tls.SelfSigned && tls.SecretName != "":
+		errs = append(errs, field.Forbidden(path.Child("secretName"), "cannot be set when selfSigned is true"))
+	case !tls.SelfSigned && tls.SecretName == "":
+		errs = append(errs, field.Required(path, "one of secretName or selfSigned must be set"))
+	}
+	return errs
+}
+
+// validateExposure checks that the exposure can reach the registry.
+// The Gateway API has no stable way to send HTTPS to a backend, an HTTPRoute would route plain HTTP
+// to a registry serving HTTPS, so TLS is terminated by the Gateway instead.
+func validateExposure(registry *registryoperatordevv1alpha1.Registry, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	exposure := registry.Spec.Exposure
+	if exposure == nil || exposure.HTTPRoute == nil || registry.Spec.TLS == nil {
+		return errs
+	}
+	errs = append(errs, field.Forbidden(path.Child("httpRoute"),
+		"cannot be set when spec.tls is set, terminate TLS on the Gateway and set spec.exposure.tls instead"))
+	return errs
+}
+
+// validateReplicas checks that the replicas can share the storage of the registry.
+// A claim that is not ReadWriteMany cannot be attached to replicas scheduled on different nodes.
+// The same rule is enforced by the CRD, so that it also holds for the scale subresource.
+func validateReplicas(registry *registryoperatordevv1alpha1.Registry, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	replicas := registry.Spec.Replicas
+	storage := registry.Spec.Storage
+	if replicas == nil || *replicas <= 1 || storage.Type != registryoperatordevv1alpha1.StorageTypeFilesystem {
+		return errs
+	}
+	if storage.Filesystem == nil || !slices.Contains(storage.Filesystem.AccessModes, corev1.ReadWriteMany) {
+		errs = append(errs, field.Forbidden(path, "cannot be greater than 1 unless the filesystem storage is ReadWriteMany"))
+	}
+	return errs
+}
+
+// validateAuth checks that the operator can serve the requested authentication,
+// and that credentials never leave the cluster in plain text.
+func (v *RegistryCustomValidator) validateAuth(registry *registryoperatordevv1alpha1.Registry, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	spec := registry.Spec
+	auth := spec.Auth
+	if auth == nil {
+		return errs
+	}
+	if auth.Token != nil && v.TokenAuth == nil {
+		errs = append(errs, field.Forbidden(path.Child("token"), "token authentication is not enabled in the operator"))
+	}
+	if (auth.Htpasswd == nil && auth.Token == nil) || spec.TLS != nil {
+		return errs
+	}
+	if spec.Service.Type == corev1.ServiceTypeNodePort || spec.Service.Type == corev1.ServiceTypeLoadBalancer {
+		errs = append(errs, field.Forbidden(path,
+			fmt.Sprintf("spec.tls must be set when the Service is of type %s", spec.Service.Type)))
+	}
+	if spec.Exposure != nil && spec.Exposure.TLS == nil {
+		errs = append(errs, field.Forbidden(path, "spec.tls or spec.exposure.tls must be set when the registry is exposed"))
+	}
+	return errs
+}
+
+// validateProxy checks that the credentials of the remote registry are not sent in plain text.
+func validateProxy(registry *registryoperatordevv1alpha1.Registry, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	proxy := registry.Spec.Proxy
+	if proxy == nil {
+		return errs
+	}
+	if proxy.CredentialsSecretRef != nil && !strings.HasPrefix(proxy.RemoteURL, "https://") {
+		errs = append(errs, field.Forbidden(path.Child("credentialsSecretRef"), "cannot be set when remoteURL is not https"))
+	}
+	if proxy.TTL != nil && proxy.TTL.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("ttl"), proxy.TTL.Duration.String(), "must be greater than zero"))
+	}
+	return errs
+}
+
+// validateDistribution checks that the distribution has a valid, non-empty selector and something to distribute.
+func validateDistribution(registry *registryoperatordevv1alpha1.Registry, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	distribution := registry.Spec.Distribution
+	if distribution == nil {
+		return errs
+	}
+	selector := distribution.NamespaceSelector
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		errs = append(errs, field.Required(path.Child("namespaceSelector"), "cannot be empty, it would select every namespace"))
+	}
+	errs = append(errs, metav1validation.ValidateLabelSelector(
+		&selector,
+		metav1validation.LabelSelectorValidationOptions{},
+		path.Child("namespaceSelector"),
+	)...)
+
+	spec := registry.Spec
+	hasCA := distribution.CAConfigMapRef != nil || (spec.TLS != nil && spec.TLS.SelfSigned)
+	hasCredentials := distribution.CredentialsSecretRef != nil ||
+		(spec.Auth != nil && spec.Auth.Htpasswd != nil && spec.Auth.Htpasswd.CredentialsSecretRef == nil)
+	if !hasCA && !hasCredentials {
+		errs = append(errs, field.Required(path,
+			"caConfigMapRef or credentialsSecretRef must be set unless the registry generates a CA or credentials"))
+	}
+	return errs
+}
+
+// validateGarbageCollection checks that the schedule is one the CronJob controller accepts.
+func validateGarbageCollection(registry *registryoperatordevv1alpha1.Registry, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	gc := registry.Spec.GarbageCollection
+	if gc == nil {
+		return errs
+	}
+	if _, err := cron.ParseStandard(gc.Schedule); err != nil {
+		errs = append(errs, field.Invalid(path.Child("schedule"), gc.Schedule, err.Error()))
+	}
+	return errs
+}
+
+// validateNotifications checks the timeouts and backoffs of the notification endpoints.
+func validateNotifications(registry *registryoperatordevv1alpha1.Registry, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	notifications := registry.Spec.Notifications
+	if notifications == nil {
+		return errs
+	}
+	for i, endpoint := range notifications.Endpoints {
+		endpointPath := path.Child("endpoints").Index(i)
+		durations := []struct {
+			name     string
+			duration *metav1.Duration
+		}{
+			{"timeout", endpoint.Timeout},
+			{"backoff", endpoint.Backoff},
+		}
+		for _, d := range durations {
+			if d.duration != nil && d.duration.Duration <= 0 {
+				errs = append(errs, field.Invalid(endpointPath.Child(d.name), d.duration.Duration.String(), "must be greater than zero"))
+			}
+		}
+	}
+	return errs
+}
+
+// validateImmutableFields rejects changes a live registry cannot follow without losing its data.
+func validateImmutableFields(oldRegistry, registry *registryoperatordevv1alpha1.Registry) field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("spec", "storage")
+	oldStorage, storage := oldRegistry.Spec.Storage, registry.Spec.Storage
+	if oldStorage.Type != storage.Type {
+		errs = append(errs, field.Forbidden(path.Child("type"), "the storage type of a registry cannot be changed"))
+	}
+
+	if oldStorage.Filesystem == nil || storage.Filesystem == nil {
+		return errs
+	}
+	// The PersistentVolumeClaim of the registry can only be expanded.
+	path = path.Child("filesystem")
+	if storage.Filesystem.Size.Cmp(oldStorage.Filesystem.Size) < 0 {
+		errs = append(errs, field.Forbidden(path.Child("size"), "the storage of a registry cannot be shrunk"))
+	}
+	if !equalStrings(oldStorage.Filesystem.StorageClassName, storage.Filesystem.StorageClassName) {
+		errs = append(errs, field.Forbidden(path.Child("storageClassName"), "field is immutable"))
+	}
+	if !slices.Equal(oldStorage.Filesystem.AccessModes, storage.Filesystem.AccessModes) {
+		errs = append(errs, field.Forbidden(path.Child("accessModes"), "field is immutable"))
+	}
+	return errs
+}
+
+// annotationWarnings warns about operator annotations that have no effect on the registry.
+func annotationWarnings(registry *registryoperatordevv1alpha1.Registry) admission.Warnings {
+	var warnings admission.Warnings
+	if strings.TrimSpace(registry.Annotations[registryoperatordevv1alpha1.MirrorsAnnotation]) != "" && registry.Spec.Proxy == nil {
+		warnings = append(warnings, fmt.Sprintf("%s is set but the registry is not a pull-through cache, "+
+			"no images are rewritten to it", registryoperatordevv1alpha1.MirrorsAnnotation))
+	}
+	if registry.Annotations[registryoperatordevv1alpha1.InjectPullSecretAnnotation] == "true" && registry.Spec.Distribution == nil {
+		warnings = append(warnings, fmt.Sprintf("%s is set but spec.distribution is not, no pull secret is distributed",
+			registryoperatordevv1alpha1.InjectPullSecretAnnotation))
+	}
+	return warnings
+}
+
+// cacheWarnings warns about registry replicas that do not share their blob descriptor cache.
+func cacheWarnings(registry *registryoperatordevv1alpha1.Registry) admission.Warnings {
+	replicas := registry.Spec.Replicas
+	if replicas == nil || *replicas <= 1 {
+		return nil
+	}
+	if cache := registry.Spec.Cache; cache != nil && cache.Type == registryoperatordevv1alpha1.CacheTypeRedis {
+		return nil
+	}
+	return admission.Warnings{"spec.replicas is greater than 1 without a redis cache, " +
+		"the replicas may answer HEAD requests inconsistently"}
+}
+
+// proxyWarnings warns about pull-through cache settings the registry image does not honour.
+func proxyWarnings(registry *registryoperatordevv1alpha1.Registry) admission.Warnings {
+	if proxy := registry.Spec.Proxy; proxy == nil || proxy.TTL == nil {
+		return nil
+	}
+	return admission.Warnings{fmt.Sprintf("spec.proxy.ttl is ignored by the %s image, "+
+		"cached content is kept for the registry default of 7 days", factories.RegistryImage)}
+}
+
+// equalStrings reports whether both strings are unset or equal.
+func equalStrings(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
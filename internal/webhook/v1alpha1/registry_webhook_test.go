@@ -0,0 +1,583 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+)
+
+// newRegistry returns a Registry with a unique name in the default namespace.
+func newRegistry(spec registryoperatordevv1alpha1.RegistrySpec) *registryoperatordevv1alpha1.Registry {
+	return &registryoperatordevv1alpha1.Registry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-" + rand.String(8), Namespace: "default"},
+		Spec:       spec,
+	}
+}
+
+// createRegistry creates the registry and removes it once the test finished.
+func createRegistry(t *testing.T, registry *registryoperatordevv1alpha1.Registry) {
+	t.Helper()
+	ctx := context.Background()
+	if err := k8sClient.Create(ctx, registry); err != nil {
+		t.Fatalf("failed to create the registry: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = k8sClient.Delete(ctx, registry)
+	})
+}
+
+// expectInvalid fails the test unless err rejects the registry as invalid, mentioning the given text.
+func expectInvalid(t *testing.T, err error, text string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected the registry to be rejected with %q", text)
+	}
+	if !apierrors.IsInvalid(err) && !apierrors.IsForbidden(err) {
+		t.Fatalf("expected the registry to be rejected as invalid, got %v", err)
+	}
+	if !strings.Contains(err.Error(), text) {
+		t.Fatalf("expected the rejection to mention %q, got %v", text, err)
+	}
+}
+
+func TestRegistryCustomDefaulter(t *testing.T) {
+	registry := newRegistry(registryoperatordevv1alpha1.RegistrySpec{
+		Storage: registryoperatordevv1alpha1.Storage{
+			Filesystem: &registryoperatordevv1alpha1.FilesystemStorage{},
+		},
+		Auth: &registryoperatordevv1alpha1.RegistryAuth{
+			Htpasswd: &registryoperatordevv1alpha1.HtpasswdAuth{},
+		},
+	})
+	if err := (&RegistryCustomDefaulter{}).Default(context.Background(), registry); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := registry.Spec
+	if spec.Storage.Type != registryoperatordevv1alpha1.StorageTypeInMemory {
+		t.Errorf("storage.type = %q, want %q", spec.Storage.Type, registryoperatordevv1alpha1.StorageTypeInMemory)
+	}
+	if size := spec.Storage.Filesystem.Size; size.Cmp(resource.MustParse("10Gi")) != 0 {
+		t.Errorf("storage.filesystem.size = %s, want 10Gi", size.String())
+	}
+	if modes := spec.Storage.Filesystem.AccessModes; len(modes) != 1 || modes[0] != corev1.ReadWriteOnce {
+		t.Errorf("storage.filesystem.accessModes = %v, want [ReadWriteOnce]", modes)
+	}
+	if spec.Replicas == nil || *spec.Replicas != 1 {
+		t.Errorf("replicas = %v, want 1", spec.Replicas)
+	}
+	if spec.Service.Type != corev1.ServiceTypeClusterIP || spec.Service.Port != 5000 {
+		t.Errorf("service = %s:%d, want ClusterIP:5000", spec.Service.Type, spec.Service.Port)
+	}
+	if realm := spec.Auth.Htpasswd.Realm; realm != "Registry Realm" {
+		t.Errorf("auth.htpasswd.realm = %q, want %q", realm, "Registry Realm")
+	}
+}
+
+func TestRegistryDefaulting(t *testing.T) {
+	requireEnvironment(t)
+	registry := newRegistry(registryoperatordevv1alpha1.RegistrySpec{
+		Storage: registryoperatordevv1alpha1.Storage{
+			Type:       registryoperatordevv1alpha1.StorageTypeFilesystem,
+			Filesystem: &registryoperatordevv1alpha1.FilesystemStorage{},
+		},
+	})
+	createRegistry(t, registry)
+
+	created := &registryoperatordevv1alpha1.Registry{}
+	if err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(registry), created); err != nil {
+		t.Fatal(err)
+	}
+	spec := created.Spec
+	if size := spec.Storage.Filesystem.Size; size.Cmp(resource.MustParse("10Gi")) != 0 {
+		t.Errorf("storage.filesystem.size = %s, want 10Gi", size.String())
+	}
+	if modes := spec.Storage.Filesystem.AccessModes; len(modes) != 1 || modes[0] != corev1.ReadWriteOnce {
+		t.Errorf("storage.filesystem.accessModes = %v, want [ReadWriteOnce]", modes)
+	}
+	if spec.Replicas == nil || *spec.Replicas != 1 {
+		t.Errorf("replicas = %v, want 1", spec.Replicas)
+	}
+	if spec.Service.Type != corev1.ServiceTypeClusterIP || spec.Service.Port != 5000 {
+		t.Errorf("service = %s:%d, want ClusterIP:5000", spec.Service.Type, spec.Service.Port)
+	}
+}
+
+func TestRegistryImmutableStorageType(t *testing.T) {
+	requireEnvironment(t)
+	registry := newRegistry(registryoperatordevv1alpha1.RegistrySpec{
+		Storage: registryoperatordevv1alpha1.Storage{Type: registryoperatordevv1alpha1.StorageTypeInMemory},
+	})
+	createRegistry(t, registry)
+
+	registry.Spec.Storage = registryoperatordevv1alpha1.Storage{
+		Type:       registryoperatordevv1alpha1.StorageTypeFilesystem,
+		Filesystem: &registryoperatordevv1alpha1.FilesystemStorage{Size: resource.MustParse("10Gi")},
+	}
+	err := k8sClient.Update(context.Background(), registry)
+	expectInvalid(t, err, "the storage type of a registry cannot be changed")
+}
+
+func TestRegistryPersistentVolumeClaimResize(t *testing.T) {
+	requireEnvironment(t)
+	ctx := context.Background()
+	registry := newRegistry(registryoperatordevv1alpha1.RegistrySpec{
+		Storage: registryoperatordevv1alpha1.Storage{
+			Type:       registryoperatordevv1alpha1.StorageTypeFilesystem,
+			Filesystem: &registryoperatordevv1alpha1.FilesystemStorage{Size: resource.MustParse("10Gi")},
+		},
+	})
+	createRegistry(t, registry)
+
+	shrunk := registry.DeepCopy()
+	shrunk.Spec.Storage.Filesystem.Size = resource.MustParse("5Gi")
+	expectInvalid(t, k8sClient.Update(ctx, shrunk), "the storage of a registry cannot be shrunk")
+
+	registry.Spec.Storage.Filesystem.Size = resource.MustParse("20Gi")
+	if err := k8sClient.Update(ctx, registry); err != nil {
+		t.Errorf("expected the storage to be expanded, got %v", err)
+	}
+}
+
+func TestRegistryScaleReadWriteOnceStorage(t *testing.T) {
+	requireEnvironment(t)
+	ctx := context.Background()
+	registry := newRegistry(registryoperatordevv1alpha1.RegistrySpec{
+		Storage: registryoperatordevv1alpha1.Storage{
+			Type:       registryoperatordevv1alpha1.StorageTypeFilesystem,
+			Filesystem: &registryoperatordevv1alpha1.FilesystemStorage{Size: resource.MustParse("10Gi")},
+		},
+	})
+	createRegistry(t, registry)
+
+	// The scale subresource bypasses the validating webhook, the CRD rejects it.
+	scale := &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 2}}
+	err := k8sClient.SubResource("scale").Update(ctx, registry, client.WithSubResourceBody(scale))
+	expectInvalid(t, err, "replicas cannot be greater than 1 unless the filesystem storage is ReadWriteMany")
+}
+
+func TestValidateReplicas(t *testing.T) {
+	filesystem := func(modes ...corev1.PersistentVolumeAccessMode) registryoperatordevv1alpha1.Storage {
+		return registryoperatordevv1alpha1.Storage{
+			Type:       registryoperatordevv1alpha1.StorageTypeFilesystem,
+			Filesystem: &registryoperatordevv1alpha1.FilesystemStorage{AccessModes: modes},
+		}
+	}
+	tests := []struct {
+		name     string
+		storage  registryoperatordevv1alpha1.Storage
+		replicas int32
+		valid    bool
+	}{
+		{name: "single ReadWriteOnce replica", storage: filesystem(corev1.ReadWriteOnce), replicas: 1, valid: true},
+		{name: "ReadWriteOnce replicas", storage: filesystem(corev1.ReadWriteOnce), replicas: 2},
+		{name: "ReadWriteMany replicas", storage: filesystem(corev1.ReadWriteMany), replicas: 3, valid: true},
+		{
+			name:     "object storage replicas",
+			storage:  registryoperatordevv1alpha1.Storage{Type: registryoperatordevv1alpha1.StorageTypeS3},
+			replicas: 3,
+			valid:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newRegistry(registryoperatordevv1alpha1.RegistrySpec{Storage: tt.storage, Replicas: &tt.replicas})
+			errs := validateReplicas(registry, nil)
+			if valid := len(errs) == 0; valid != tt.valid {
+				t.Errorf("validateReplicas() = %v, want valid %t", errs, tt.valid)
+			}
+		})
+	}
+}
+
+func TestRegistryStorageRequiredFields(t *testing.T) {
+	requireEnvironment(t)
+	credentials := corev1.LocalObjectReference{Name: "credentials"}
+	tests := []struct {
+		name    string
+		storage registryoperatordevv1alpha1.Storage
+		message string
+	}{
+		{
+			name:    "filesystem without filesystem",
+			storage: registryoperatordevv1alpha1.Storage{Type: registryoperatordevv1alpha1.StorageTypeFilesystem},
+			message: "filesystem must be set when storage type is filesystem",
+		},
+		{
+			name:    "s3 without s3",
+			storage: registryoperatordevv1alpha1.Storage{Type: registryoperatordevv1alpha1.StorageTypeS3},
+			message: "s3 must be set when storage type is s3",
+		},
+		{
+			name: "s3 without bucket",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeS3,
+				S3:   &registryoperatordevv1alpha1.S3Storage{Region: "us-east-1"},
+			},
+			message: "spec.storage.s3.bucket",
+		},
+		{
+			name:    "gcs without gcs",
+			storage: registryoperatordevv1alpha1.Storage{Type: registryoperatordevv1alpha1.StorageTypeGCS},
+			message: "gcs must be set when storage type is gcs",
+		},
+		{
+			name:    "azure without azure",
+			storage: registryoperatordevv1alpha1.Storage{Type: registryoperatordevv1alpha1.StorageTypeAzure},
+			message: "azure must be set when storage type is azure",
+		},
+		{
+			name: "azure without container",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeAzure,
+				Azure: &registryoperatordevv1alpha1.AzureStorage{
+					AccountName:          "registry",
+					CredentialsSecretRef: credentials,
+				},
+			},
+			message: "spec.storage.azure.container",
+		},
+		{
+			name: "driver of another storage type",
+			storage: registryoperatordevv1alpha1.Storage{
+				Type: registryoperatordevv1alpha1.StorageTypeInMemory,
+				S3: &registryoperatordevv1alpha1.S3Storage{
+					Bucket: "registry",
+					Region: "us-east-1",
+				},
+			},
+			message: "can only be set when storage type is s3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newRegistry(registryoperatordevv1alpha1.RegistrySpec{Storage: tt.storage})
+			expectInvalid(t, k8sClient.Create(context.Background(), registry), tt.message)
+		})
+	}
+}
+
+func TestRegistryCrossFieldValidation(t *testing.T) {
+	requireEnvironment(t)
+	filesystem := registryoperatordevv1alpha1.Storage{
+		Type:       registryoperatordevv1alpha1.StorageTypeFilesystem,
+		Filesystem: &registryoperatordevv1alpha1.FilesystemStorage{Size: resource.MustParse("10Gi")},
+	}
+	inmemory := registryoperatordevv1alpha1.Storage{Type: registryoperatordevv1alpha1.StorageTypeInMemory}
+	htpasswd := &registryoperatordevv1alpha1.RegistryAuth{Htpasswd: &registryoperatordevv1alpha1.HtpasswdAuth{}}
+	exposure := &registryoperatordevv1alpha1.Exposure{
+		Host:    "registry.example.com",
+		Ingress: &registryoperatordevv1alpha1.IngressExposure{},
+	}
+
+	tests := []struct {
+		name    string
+		spec    registryoperatordevv1alpha1.RegistrySpec
+		message string
+	}{
+		{
+			name: "self-signed TLS with a certificate Secret",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage: inmemory,
+				TLS:     &registryoperatordevv1alpha1.RegistryTLS{SelfSigned: true, SecretName: "tls"},
+			},
+			message: "exactly one of secretName and selfSigned must be set",
+		},
+		{
+			name: "htpasswd exposed without TLS",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage:  inmemory,
+				Auth:     htpasswd,
+				Exposure: exposure,
+			},
+			message: "spec.tls or spec.exposure.tls must be set when the registry is exposed",
+		},
+		{
+			name: "htpasswd on a LoadBalancer without TLS",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage: inmemory,
+				Auth:    htpasswd,
+				Service: registryoperatordevv1alpha1.RegistryService{Type: corev1.ServiceTypeLoadBalancer},
+			},
+			message: "spec.tls must be set when the Service is of type LoadBalancer",
+		},
+		{
+			name: "HTTPRoute in front of a registry serving HTTPS",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage: inmemory,
+				TLS:     &registryoperatordevv1alpha1.RegistryTLS{SelfSigned: true},
+				Exposure: &registryoperatordevv1alpha1.Exposure{
+					Host: "registry.example.com",
+					HTTPRoute: &registryoperatordevv1alpha1.HTTPRouteExposure{
+						ParentRefs: []registryoperatordevv1alpha1.ParentReference{{Name: "gateway"}},
+					},
+				},
+			},
+			message: "spec.exposure.httpRoute: Forbidden",
+		},
+		{
+			name: "token authentication disabled in the operator",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage: inmemory,
+				Auth:    &registryoperatordevv1alpha1.RegistryAuth{Token: &registryoperatordevv1alpha1.TokenAuth{}},
+				TLS:     &registryoperatordevv1alpha1.RegistryTLS{SelfSigned: true},
+			},
+			message: "token authentication is not enabled in the operator",
+		},
+		{
+			name: "proxy credentials over plain HTTP",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage: inmemory,
+				Proxy: &registryoperatordevv1alpha1.RegistryProxy{
+					RemoteURL:            "http://upstream:5000",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "credentials"},
+				},
+			},
+			message: "cannot be set when remoteURL is not https",
+		},
+		{
+			name: "distribution to every namespace",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage:      inmemory,
+				TLS:          &registryoperatordevv1alpha1.RegistryTLS{SelfSigned: true},
+				Distribution: &registryoperatordevv1alpha1.RegistryDistribution{},
+			},
+			message: "spec.distribution.namespaceSelector: Required value",
+		},
+		{
+			name: "garbage collection of inmemory storage",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage:           inmemory,
+				GarbageCollection: &registryoperatordevv1alpha1.GarbageCollection{Schedule: "0 3 * * *"},
+			},
+			message: "garbageCollection cannot be set with inmemory storage",
+		},
+		{
+			name: "garbage collection of a proxy",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage:           filesystem,
+				Proxy:             &registryoperatordevv1alpha1.RegistryProxy{RemoteURL: "https://registry-1.docker.io"},
+				GarbageCollection: &registryoperatordevv1alpha1.GarbageCollection{Schedule: "0 3 * * *"},
+			},
+			message: "garbageCollection cannot be set on a proxy",
+		},
+		{
+			name: "deletes on a proxy",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage: registryoperatordevv1alpha1.Storage{
+					Type:   registryoperatordevv1alpha1.StorageTypeInMemory,
+					Delete: &registryoperatordevv1alpha1.StorageDelete{Enabled: true},
+				},
+				Proxy: &registryoperatordevv1alpha1.RegistryProxy{RemoteURL: "https://registry-1.docker.io"},
+			},
+			message: "storage.delete cannot be enabled on a proxy",
+		},
+		{
+			name: "replicas sharing a ReadWriteOnce claim",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage:  filesystem,
+				Replicas: ptr.To[int32](2),
+			},
+			message: "replicas cannot be greater than 1 unless the filesystem storage is ReadWriteMany",
+		},
+		{
+			name: "invalid garbage collection schedule",
+			spec: registryoperatordevv1alpha1.RegistrySpec{
+				Storage:           filesystem,
+				GarbageCollection: &registryoperatordevv1alpha1.GarbageCollection{Schedule: "every night"},
+			},
+			message: "spec.garbageCollection.schedule",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newRegistry(tt.spec)
+			expectInvalid(t, k8sClient.Create(context.Background(), registry), tt.message)
+		})
+	}
+
+	t.Run("htpasswd exposed with TLS", func(t *testing.T) {
+		tlsExposure := exposure.DeepCopy()
+		tlsExposure.TLS = &registryoperatordevv1alpha1.ExposureTLS{SecretName: "registry-tls"}
+		createRegistry(t, newRegistry(registryoperatordevv1alpha1.RegistrySpec{
+			Storage:  inmemory,
+			Auth:     htpasswd,
+			Exposure: tlsExposure,
+		}))
+	})
+}
+
+func TestValidateTLS(t *testing.T) {
+	tests := []struct {
+		name  string
+		tls   *registryoperatordevv1alpha1.RegistryTLS
+		valid bool
+	}{
+		{name: "unset", valid: true},
+		{name: "self-signed", tls: &registryoperatordevv1alpha1.RegistryTLS{SelfSigned: true}, valid: true},
+		{name: "certificate Secret", tls: &registryoperatordevv1alpha1.RegistryTLS{SecretName: "tls"}, valid: true},
+		{name: "both", tls: &registryoperatordevv1alpha1.RegistryTLS{SelfSigned: true, SecretName: "tls"}},
+		{name: "neither", tls: &registryoperatordevv1alpha1.RegistryTLS{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newRegistry(registryoperatordevv1alpha1.RegistrySpec{TLS: tt.tls})
+			errs := validateTLS(registry, nil)
+			if valid := len(errs) == 0; valid != tt.valid {
+				t.Errorf("validateTLS() = %v, want valid %t", errs, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateExposure(t *testing.T) {
+	httpRoute := &registryoperatordevv1alpha1.Exposure{
+		Host: "registry.example.com",
+		HTTPRoute: &registryoperatordevv1alpha1.HTTPRouteExposure{
+			ParentRefs: []registryoperatordevv1alpha1.ParentReference{{Name: "gateway"}},
+		},
+	}
+	ingress := &registryoperatordevv1alpha1.Exposure{
+		Host:    "registry.example.com",
+		Ingress: &registryoperatordevv1alpha1.IngressExposure{},
+	}
+	selfSigned := &registryoperatordevv1alpha1.RegistryTLS{SelfSigned: true}
+
+	tests := []struct {
+		name     string
+		exposure *registryoperatordevv1alpha1.Exposure
+		tls      *registryoperatordevv1alpha1.RegistryTLS
+		valid    bool
+	}{
+		{name: "unset", valid: true},
+		{name: "HTTPRoute", exposure: httpRoute, valid: true},
+		{name: "HTTPRoute with TLS", exposure: httpRoute, tls: selfSigned},
+		{name: "Ingress with TLS", exposure: ingress, tls: selfSigned, valid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newRegistry(registryoperatordevv1alpha1.RegistrySpec{Exposure: tt.exposure, TLS: tt.tls})
+			errs := validateExposure(registry, nil)
+			if valid := len(errs) == 0; valid != tt.valid {
+				t.Errorf("validateExposure() = %v, want valid %t", errs, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateProxy(t *testing.T) {
+	ttl := func(d time.Duration) *metav1.Duration { return &metav1.Duration{Duration: d} }
+	credentials := &corev1.LocalObjectReference{Name: "docker-hub"}
+
+	tests := []struct {
+		name        string
+		proxy       *registryoperatordevv1alpha1.RegistryProxy
+		valid       bool
+		wantWarning bool
+	}{
+		{name: "unset", valid: true},
+		{
+			name:  "anonymous",
+			proxy: &registryoperatordevv1alpha1.RegistryProxy{RemoteURL: "http://upstream.default.svc:5000"},
+			valid: true,
+		},
+		{
+			name:  "credentials over https",
+			proxy: &registryoperatordevv1alpha1.RegistryProxy{RemoteURL: "https://registry-1.docker.io", CredentialsSecretRef: credentials},
+			valid: true,
+		},
+		{
+			name:  "credentials over http",
+			proxy: &registryoperatordevv1alpha1.RegistryProxy{RemoteURL: "http://upstream.default.svc:5000", CredentialsSecretRef: credentials},
+		},
+		{
+			name:        "ttl",
+			proxy:       &registryoperatordevv1alpha1.RegistryProxy{RemoteURL: "https://registry-1.docker.io", TTL: ttl(72 * time.Hour)},
+			valid:       true,
+			wantWarning: true,
+		},
+		{
+			name:        "zero ttl",
+			proxy:       &registryoperatordevv1alpha1.RegistryProxy{RemoteURL: "https://registry-1.docker.io", TTL: ttl(0)},
+			wantWarning: true,
+		},
+		{
+			name:        "negative ttl",
+			proxy:       &registryoperatordevv1alpha1.RegistryProxy{RemoteURL: "https://registry-1.docker.io", TTL: ttl(-time.Hour)},
+			wantWarning: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newRegistry(registryoperatordevv1alpha1.RegistrySpec{Proxy: tt.proxy})
+			errs := validateProxy(registry, nil)
+			if valid := len(errs) == 0; valid != tt.valid {
+				t.Errorf("validateProxy() = %v, want valid %t", errs, tt.valid)
+			}
+			if warned := len(proxyWarnings(registry)) > 0; warned != tt.wantWarning {
+				t.Errorf("proxyWarnings() = %v, want a warning %t", proxyWarnings(registry), tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestValidateDistribution(t *testing.T) {
+	selector := metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+	selfSigned := &registryoperatordevv1alpha1.RegistryTLS{SelfSigned: true}
+
+	tests := []struct {
+		name         string
+		distribution *registryoperatordevv1alpha1.RegistryDistribution
+		tls          *registryoperatordevv1alpha1.RegistryTLS
+		valid        bool
+	}{
+		{name: "unset", valid: true},
+		{
+			name:         "generated CA",
+			distribution: &registryoperatordevv1alpha1.RegistryDistribution{NamespaceSelector: selector},
+			tls:          selfSigned,
+			valid:        true,
+		},
+		{
+			name: "selector with expressions only",
+			distribution: &registryoperatordevv1alpha1.RegistryDistribution{
+				NamespaceSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      "team",
+					Operator: metav1.LabelSelectorOpExists,
+				}}},
+			},
+			tls:   selfSigned,
+			valid: true,
+		},
+		{
+			name:         "empty selector",
+			distribution: &registryoperatordevv1alpha1.RegistryDistribution{},
+			tls:          selfSigned,
+		},
+		{
+			name:         "nothing to distribute",
+			distribution: &registryoperatordevv1alpha1.RegistryDistribution{NamespaceSelector: selector},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newRegistry(registryoperatordevv1alpha1.RegistrySpec{Distribution: tt.distribution, TLS: tt.tls})
+			errs := validateDistribution(registry, nil)
+			if valid := len(errs) == 0; valid != tt.valid {
+				t.Errorf("validateDistribution() = %v, want valid %t", errs, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package v1alpha1
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	registryoperatordevv1alpha1 "github.com/registry-operator/registry-operator/api/v1alpha1"
+)
+
+// k8sClient talks to the API server of the test environment, it is nil when no environment is available.
+var k8sClient client.Client
+
+// TestMain starts an API server with the Registry CRD and the webhooks of this package registered.
+// The binaries of the API server are found through KUBEBUILDER_ASSETS, see the test target of the Makefile.
+func TestMain(m *testing.M) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		os.Exit(m.Run())
+	}
+
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "..", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join("..", "..", "..", "config", "webhook", "manifests.yaml")},
+		},
+	}
+	if _, err := testEnv.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start the test environment: %v\n", err)
+		os.Exit(1)
+	}
+
+	code, err := runWithWebhooks(m, testEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		code = 1
+	}
+	if err := testEnv.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to stop the test environment: %v\n", err)
+	}
+	os.Exit(code)
+}
+
+// runWithWebhooks serves the webhooks of this package for the test environment while the tests run.
+func runWithWebhooks(m *testing.M, testEnv *envtest.Environment) (int, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return 0, err
+	}
+	if err := registryoperatordevv1alpha1.AddToScheme(scheme); err != nil {
+		return 0, err
+	}
+
+	var err error
+	k8sClient, err = client.New(testEnv.Config, client.Options{Scheme: scheme})
+	if err != nil {
+		return 0, err
+	}
+
+	options := &testEnv.WebhookInstallOptions
+	mgr, err := ctrl.NewManager(testEnv.Config, ctrl.Options{
+		Scheme: scheme,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    options.LocalServingHost,
+			Port:    options.LocalServingPort,
+			CertDir: options.LocalServingCertDir,
+		}),
+		LeaderElection: false,
+		Metrics:        metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := SetupRegistryWebhookWithManager(mgr, nil); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := mgr.Start(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start the manager: %v\n", err)
+		}
+	}()
+
+	// The API server only reaches the webhooks once they serve.
+	address := net.JoinHostPort(options.LocalServingHost, fmt.Sprint(options.LocalServingPort))
+	dialer := &net.Dialer{Timeout: time.Second}
+	for deadline := time.Now().Add(30 * time.Second); ; time.Sleep(100 * time.Millisecond) {
+		conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true}) //nolint:gosec
+		if err == nil {
+			_ = conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("webhook server is not serving: %w", err)
+		}
+	}
+	return m.Run(), nil
+}
+
+// requireEnvironment skips tests that need an API server when no test environment is available.
+func requireEnvironment(t *testing.T) {
+	t.Helper()
+	if k8sClient == nil {
+		t.Skip("KUBEBUILDER_ASSETS is not set, run the tests with make test")
+	}
+}